@@ -16,9 +16,12 @@ SRTSOCKET srt_accept_wrapped(SRTSOCKET lsn, struct sockaddr* addr, int* addrlen,
 */
 import "C"
 import (
+	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -37,10 +40,31 @@ func srtAcceptImpl(lsn C.SRTSOCKET, addr *C.struct_sockaddr, addrlen *C.int) (C.
 }
 
 // Accept an incoming connection
+//
+// On a listener with groupconnect enabled, a caller that connects a socket
+// group (bonding) opens one member connection per link. SRT runs the listen
+// callback for every member, with the member socket, and creates a group on
+// the listener side when the first member of a group completes the handshake;
+// Accept then returns that group, for which IsGroup reports true, not the
+// member socket. Members of the same caller group that connect later join the
+// accepted group and are not returned by Accept again. Use GroupMembers to
+// correlate the member links of an accepted group, and GroupID to find the
+// group a member socket belongs to. Listeners that do not enable
+// groupconnect reject grouped callers.
 func (s SrtSocket) Accept() (*SrtSocket, *net.UDPAddr, error) {
+	if s.blocking {
+		s.state.acceptTurn <- struct{}{}
+		defer func() { <-s.state.acceptTurn }()
+	}
+	return s.accept(time.Time{})
+}
+
+// accept implements Accept, a non-blocking listener waits for a connection at
+// most until deadline unless it is zero
+func (s SrtSocket) accept(deadline time.Time) (*SrtSocket, *net.UDPAddr, error) {
 	var err error
 	if !s.blocking {
-		err = s.pd.wait(ModeRead)
+		err = s.pd.waitUntil(deadline, ModeRead)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -55,10 +79,16 @@ func (s SrtSocket) Accept() (*SrtSocket, *net.UDPAddr, error) {
 		return nil, nil, fmt.Errorf("srt accept, error accepting the connection: %w", srtGetAndClearError())
 	}
 
-	newSocket, err := newFromSocket(&s, socket)
+	pending := s.state.acceptPending(socket)
+	state := pending
+	if state == nil {
+		state = newSocketState()
+	}
+	newSocket, err := newFromSocket(&s, socket, state)
 	if err != nil {
 		return nil, nil, fmt.Errorf("new socket could not be created: %w", err)
 	}
+	newSocket.acceptedConnectDuration(pending)
 
 	udpAddr, err := udpAddrFromSockaddr(&addr)
 	if err != nil {
@@ -67,3 +97,171 @@ func (s SrtSocket) Accept() (*SrtSocket, *net.UDPAddr, error) {
 
 	return newSocket, udpAddr, nil
 }
+
+// AcceptTimeout accepts an incoming connection like Accept, but gives up after
+// d and returns a SrtEpollTimeout error (Timeout reports true) if no
+// connection arrived by then.
+//
+// On a non-blocking listener the timeout bounds the wait of this call only: it
+// does not change the listener's read deadline, so it does not end Accept
+// calls on the same listener, and an earlier read deadline set with
+// SetReadDeadline still applies. Waits on one listener are serialized, so
+// AcceptTimeout may return later than d when it queues behind a concurrent
+// Accept.
+//
+// A blocking listener has no poll descriptor, so the wait uses an epoll
+// container of its own that is released before AcceptTimeout returns; neither
+// leaves registrations behind, however often the accept times out. Accepts of
+// a blocking listener are serialized, and AcceptTimeout switches the listener
+// to non-blocking accept while it holds its turn, so a connection that broke
+// in the queue makes it wait again rather than block beyond d. It times out
+// without a connection while a concurrent Accept waits, as that Accept takes
+// the next connection.
+func (s SrtSocket) AcceptTimeout(d time.Duration) (*SrtSocket, *net.UDPAddr, error) {
+	if d <= 0 {
+		return nil, nil, fmt.Errorf("accept timeout must be positive, got %v", d)
+	}
+	if s.blocking {
+		return s.acceptBlockingUntil(time.Now().Add(d))
+	}
+	return s.accept(time.Now().Add(d))
+}
+
+// acceptBlockingUntil implements AcceptTimeout for a blocking listener
+func (s SrtSocket) acceptBlockingUntil(deadline time.Time) (*SrtSocket, *net.UDPAddr, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case s.state.acceptTurn <- struct{}{}:
+	case <-timer.C:
+		return nil, nil, &SrtEpollTimeout{}
+	}
+	defer func() { <-s.state.acceptTurn }()
+
+	// No other accept runs while the turn is held. Accepted sockets get their
+	// own RCVSYN in postconfiguration.
+	if err := setSyncOption(s.socket, C.SRTO_RCVSYN, false); err != nil {
+		return nil, nil, err
+	}
+	defer setSyncOption(s.socket, C.SRTO_RCVSYN, true)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil, &SrtEpollTimeout{}
+		}
+		if err := s.waitAcceptable(remaining); err != nil {
+			return nil, nil, err
+		}
+		newSocket, addr, err := s.accept(time.Time{})
+		if errors.Is(err, error(EAsyncRCV)) {
+			continue
+		}
+		return newSocket, addr, err
+	}
+}
+
+// waitAcceptable waits up to d for a pending connection on a blocking listener
+func (s SrtSocket) waitAcceptable(d time.Duration) error {
+	eid := C.srt_epoll_create()
+	if eid < 0 {
+		return fmt.Errorf("srt_epoll_create: %w", srtGetAndClearErrorThreadSafe())
+	}
+	// Releasing the container also drops the listener's subscription
+	defer C.srt_epoll_release(eid)
+
+	events := C.int(C.SRT_EPOLL_IN | C.SRT_EPOLL_ERR)
+	if C.srt_epoll_add_usock(eid, s.socket, &events) == SRT_ERROR {
+		return fmt.Errorf("srt_epoll_add_usock: %w", srtGetAndClearErrorThreadSafe())
+	}
+	var ready [1]C.SRT_EPOLL_EVENT
+	res, err := srtEpollUWaitImpl(eid, ready[:], C.int64_t(d/time.Millisecond))
+	if err != nil {
+		if errors.Is(err, ETimeout) {
+			return &SrtEpollTimeout{}
+		}
+		return fmt.Errorf("srt_epoll_uwait: %w", err)
+	}
+	if res == 0 {
+		return &SrtEpollTimeout{}
+	}
+	// On SRT_EPOLL_ERR Accept reports the listener's error
+	return nil
+}
+
+// maxStreamIDLen is the maximum length of a streamid accepted by SRT
+const maxStreamIDLen = 512
+
+// AcceptWithStreamID accepts an incoming connection like Accept and also
+// returns the streamid the caller connected with, which is what listeners
+// usually route on. The streamid is empty if the caller did not set one.
+func (s SrtSocket) AcceptWithStreamID() (*SrtSocket, *net.UDPAddr, string, error) {
+	newSocket, addr, err := s.Accept()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	buf := make([]byte, maxStreamIDLen)
+	l := len(buf)
+	if err := newSocket.getSockOpt(SRTO_STREAMID, unsafe.Pointer(&buf[0]), &l); err != nil {
+		newSocket.Close()
+		return nil, nil, "", err
+	}
+	return newSocket, addr, string(buf[:l]), nil
+}
+
+// Serve accepts connections on the listener and runs handler for each of
+// them in its own goroutine, with at most maxConcurrent handlers running at
+// once. When the limit is reached Serve stops accepting until a handler
+// returns, so pending connections wait in the listen backlog (and are rejected
+// by SRT when it overflows). The accepted socket is closed when its handler
+// returns.
+//
+// Serve returns once the listener is closed, after waiting for the running
+// handlers to finish, so closing the listener drains the server gracefully;
+// handlers that should stop early must be signalled separately. Like
+// net/http, other accept errors, e.g. an expired read deadline or a
+// connection that broke before it could be set up, are retried with a backoff
+// of up to one second while the listener is still listening; if it is not,
+// the error is returned, also after draining.
+func (s SrtSocket) Serve(handler func(*SrtSocket), maxConcurrent int) error {
+	if maxConcurrent <= 0 {
+		return fmt.Errorf("maxConcurrent must be positive, got %d", maxConcurrent)
+	}
+	sem := make(chan struct{}, maxConcurrent)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	var tempDelay time.Duration // how long to sleep on accept failure
+	for {
+		sem <- struct{}{}
+		socket, _, err := s.Accept()
+		if err != nil {
+			<-sem
+			if s.isClosed() {
+				return nil
+			}
+			if C.srt_getsockstate(s.socket) != C.SRTS_LISTENING {
+				return err
+			}
+			if tempDelay == 0 {
+				tempDelay = 5 * time.Millisecond
+			} else {
+				tempDelay *= 2
+			}
+			if max := 1 * time.Second; tempDelay > max {
+				tempDelay = max
+			}
+			time.Sleep(tempDelay)
+			continue
+		}
+		tempDelay = 0
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer socket.Close()
+			handler(socket)
+		}()
+	}
+}
@@ -0,0 +1,191 @@
+package srtgo
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+
+import (
+	"fmt"
+	"time"
+)
+
+// listenBacklog tracks the connections of a listener between its listen
+// callback and Accept. SRT does not expose the length of the accept queue, so
+// it is estimated from the connections the callback admitted.
+type listenBacklog struct {
+	// pending holds the state of the connections admitted by the listen
+	// callback that have not been accepted yet, keyed by the id of their
+	// socket; Accept hands it to the accepted socket
+	pending map[C.int]*socketState
+	// groups are the socket groups returned by Accept, members that join
+	// them later are not pending
+	groups map[C.int]struct{}
+}
+
+// backlogLocked returns the backlog of a listener, creating it if needed.
+// Must be called with st.mu held.
+func (st *socketState) backlogLocked() *listenBacklog {
+	if st.backlog == nil {
+		st.backlog = &listenBacklog{
+			pending: make(map[C.int]*socketState),
+			groups:  make(map[C.int]struct{}),
+		}
+	}
+	return st.backlog
+}
+
+// trackListenBacklog marks the socket as listening
+func (st *socketState) trackListenBacklog() {
+	st.mu.Lock()
+	st.backlogLocked()
+	st.mu.Unlock()
+}
+
+// admitPending returns the state of a connection the listen callback of the
+// listener with state st admits. SRT may run the callback again for a
+// retransmitted handshake of the same connection, which gets the same state.
+func (st *socketState) admitPending(socket C.int) *socketState {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	b := st.backlogLocked()
+	if pending, exists := b.pending[socket]; exists {
+		return pending
+	}
+	// Connections that failed after the callback admitted them are not
+	// accepted, so forget them here even if PendingConnections is never called
+	b.pruneLocked()
+	pending := newSocketState()
+	pending.handshakeStart = time.Now()
+	b.pending[socket] = pending
+	return pending
+}
+
+// dropPending forgets a connection the listen callback rejected
+func (st *socketState) dropPending(socket C.int) {
+	st.mu.Lock()
+	if st.backlog != nil {
+		delete(st.backlog.pending, socket)
+	}
+	st.mu.Unlock()
+}
+
+// acceptPending removes a socket returned by Accept from the backlog and
+// returns the state the listen callback created for it, nil if there is none.
+// For a socket group the pending member connections are removed, and the
+// state is the one of the member admitted first.
+func (st *socketState) acceptPending(socket C.int) *socketState {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.backlog == nil {
+		return nil
+	}
+	b := st.backlog
+	defer b.pruneLocked()
+	if socket&C.SRTGROUP_MASK == 0 {
+		pending := b.pending[socket]
+		delete(b.pending, socket)
+		return pending
+	}
+
+	b.groups[socket] = struct{}{}
+	var first *socketState
+	for member, pending := range b.pending {
+		if C.srt_groupof(member) != socket {
+			continue
+		}
+		delete(b.pending, member)
+		if first == nil || pending.handshakeStart.Before(first.handshakeStart) {
+			first = pending
+		}
+	}
+	return first
+}
+
+// pendingRegisterGrace is how long a connection admitted by the listen
+// callback may remain unknown to SRT before it is considered failed
+const pendingRegisterGrace = time.Second
+
+// pruneLocked forgets the connections that can no longer be accepted: the
+// ones that failed in the handshake, e.g. on a bad passphrase, or timed out in
+// the queue, and members that joined a group accepted before. It also forgets
+// accepted groups that were closed since. Must be called with st.mu held.
+func (b *listenBacklog) pruneLocked() {
+	for group := range b.groups {
+		var size C.size_t
+		if C.srt_group_data(group, nil, &size) == SRT_ERROR {
+			// Closed since it was accepted
+			delete(b.groups, group)
+		}
+	}
+	for socket, pending := range b.pending {
+		switch C.srt_getsockstate(socket) {
+		case C.SRTS_BROKEN, C.SRTS_CLOSING, C.SRTS_CLOSED:
+			delete(b.pending, socket)
+			continue
+		case C.SRTS_NONEXIST:
+			// SRT registers the socket only after the callback returns
+			if time.Since(pending.handshakeStart) > pendingRegisterGrace {
+				delete(b.pending, socket)
+			}
+			continue
+		}
+		if _, accepted := b.groups[C.srt_groupof(socket)]; accepted {
+			delete(b.pending, socket)
+		}
+	}
+}
+
+// countPending returns the number of connections admitted by the listen
+// callback that can still be accepted, forgetting the ones that cannot
+func (st *socketState) countPending() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	b := st.backlogLocked()
+	b.pruneLocked()
+
+	n := 0
+	groups := make(map[C.int]struct{})
+	for socket := range b.pending {
+		group := C.srt_groupof(socket)
+		if group == SRT_INVALID_SOCK {
+			n++
+			continue
+		}
+		// The members of a group are accepted as one connection
+		groups[group] = struct{}{}
+	}
+	return n + len(groups)
+}
+
+// PendingConnections returns an estimate of the number of connections waiting
+// in the accept queue of a listening socket. A value that keeps growing under
+// load means the accept loop does not keep up with incoming connections.
+//
+// SRT provides no way to query the queue, so the connections are tracked from
+// the listen callback to Accept, which requires a listen callback to be set
+// with SetListenCallback before Listen (a nil callback admits every
+// connection). Connections that fail in the handshake or time out in the
+// queue stop counting once SRT has closed their socket, and the member links
+// of a socket group (see groupconnect) count as one connection. It is
+// approximate: a connection admitted by the callback may still be rejected
+// later in the handshake, e.g. when the backlog is full, and counts until
+// SRT has released it.
+func (s SrtSocket) PendingConnections() (int, error) {
+	if s.state.backlogOf() == nil {
+		return 0, fmt.Errorf("socket is not listening")
+	}
+	callbackMutex.Lock()
+	_, hasListenCallback := listenCallbackMap[s.socket]
+	callbackMutex.Unlock()
+	if !hasListenCallback {
+		return 0, fmt.Errorf("PendingConnections requires a listen callback")
+	}
+	return s.state.countPending(), nil
+}
+
+// backlogOf returns the backlog of a listener, nil if it is not listening
+func (st *socketState) backlogOf() *listenBacklog {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.backlog
+}
@@ -0,0 +1,124 @@
+package srtgo
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// BWMode selects how SRT limits the sending bandwidth (maxbw, inputbw and oheadbw options)
+type BWMode int
+
+const (
+	// BWModeUnlimited - no limit (maxbw = -1). In live mode SRT caps at 1 Gbps.
+	BWModeUnlimited BWMode = iota
+	// BWModeAbsolute - fixed limit (maxbw = rate); the overhead is not used
+	BWModeAbsolute
+	// BWModeInput - limit relative to a known input rate
+	// (maxbw = 0, inputbw = rate, oheadbw = overhead)
+	BWModeInput
+	// BWModeEstimated - limit relative to the input rate as measured by SRT,
+	// never assuming less than the given rate
+	// (maxbw = 0, inputbw = 0, mininputbw = rate, oheadbw = overhead)
+	BWModeEstimated
+)
+
+// Overhead percentage range accepted by SRT for oheadbw
+const (
+	minOverheadPct = 5
+	maxOverheadPct = 100
+)
+
+// String returns human-readable bandwidth mode name
+func (m BWMode) String() string {
+	switch m {
+	case BWModeUnlimited:
+		return "unlimited"
+	case BWModeAbsolute:
+		return "absolute"
+	case BWModeInput:
+		return "input"
+	case BWModeEstimated:
+		return "estimated"
+	default:
+		return "unknown"
+	}
+}
+
+// SetMaxBandwidth configures the sending bandwidth limit. inputBps is a
+// bitrate in bits per second (SRT options use bytes per second; the conversion
+// is done here). Depending on mode it is the absolute limit, the known input
+// rate or the minimum input rate, and overheadPct (5 to 100) is the share of
+// bandwidth reserved for retransmissions on top of the input rate. inputBps is
+// ignored by BWModeUnlimited. overheadPct must be 0 with BWModeUnlimited and
+// BWModeAbsolute: SRT only applies oheadbw when maxbw is 0 (relative mode) and
+// silently ignores it otherwise, so a non-zero overhead is rejected rather than
+// left without effect. These are POST options, so this can be called on a
+// connected socket.
+func (s SrtSocket) SetMaxBandwidth(mode BWMode, inputBps int64, overheadPct int) error {
+	opts, err := maxBandwidthOptions(mode, inputBps, overheadPct)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		if err := s.setOption(opt[0], opt[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxBandwidthOptions returns the options to set, in order, for a bandwidth mode
+func maxBandwidthOptions(mode BWMode, inputBps int64, overheadPct int) ([][2]string, error) {
+	rate := strconv.FormatInt(inputBps/8, 10)
+	overhead := strconv.Itoa(overheadPct)
+
+	if (mode == BWModeUnlimited || mode == BWModeAbsolute) && overheadPct != 0 {
+		return nil, fmt.Errorf("overhead %d%% has no effect in %s bandwidth mode: oheadbw only applies when maxbw is 0 (input or estimated mode)", overheadPct, mode)
+	}
+
+	switch mode {
+	case BWModeUnlimited:
+		return [][2]string{{"maxbw", "-1"}}, nil
+	case BWModeAbsolute:
+		if inputBps <= 0 {
+			return nil, fmt.Errorf("absolute bandwidth limit must be positive, got %d", inputBps)
+		}
+		return [][2]string{{"maxbw", rate}}, nil
+	case BWModeInput, BWModeEstimated:
+		if inputBps <= 0 {
+			return nil, fmt.Errorf("input bitrate must be positive, got %d", inputBps)
+		}
+		if overheadPct < minOverheadPct || overheadPct > maxOverheadPct {
+			return nil, fmt.Errorf("overhead %d%% out of range [%d, %d]", overheadPct, minOverheadPct, maxOverheadPct)
+		}
+		if mode == BWModeInput {
+			return [][2]string{{"inputbw", rate}, {"oheadbw", overhead}, {"maxbw", "0"}}, nil
+		}
+		return [][2]string{{"inputbw", "0"}, {"mininputbw", rate}, {"oheadbw", overhead}, {"maxbw", "0"}}, nil
+	}
+	return nil, fmt.Errorf("unknown bandwidth mode %d", mode)
+}
+
+// ValidateBandwidthOptions checks an options map for an oheadbw setting that
+// SRT would silently ignore. The overhead only applies in relative mode, when
+// maxbw is 0 and the limit is derived from inputbw (or the measured input rate,
+// never less than mininputbw) plus oheadbw percent. With maxbw -1 (unlimited,
+// the default) or a positive maxbw (absolute limit) the overhead has no effect,
+// so setting oheadbw without maxbw=0 is reported as an error.
+func ValidateBandwidthOptions(options map[string]string) error {
+	if _, ok := options["oheadbw"]; !ok {
+		return nil
+	}
+	maxbw, ok := options["maxbw"]
+	if !ok {
+		return fmt.Errorf("oheadbw has no effect without maxbw=0: maxbw defaults to -1 (unlimited)")
+	}
+	v, err := strconv.ParseInt(maxbw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid maxbw value: %s", maxbw)
+	}
+	if v != 0 {
+		return fmt.Errorf("oheadbw has no effect with maxbw=%d: it only applies when maxbw is 0", v)
+	}
+	return nil
+}
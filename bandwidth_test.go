@@ -0,0 +1,63 @@
+package srtgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaxBandwidthOptions(t *testing.T) {
+	opts, err := maxBandwidthOptions(BWModeInput, 8000000, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]string{{"inputbw", "1000000"}, {"oheadbw", "25"}, {"maxbw", "0"}}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("expected %v, got %v", expected, opts)
+	}
+
+	opts, err = maxBandwidthOptions(BWModeAbsolute, 8000000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opts, [][2]string{{"maxbw", "1000000"}}) {
+		t.Errorf("unexpected absolute options %v", opts)
+	}
+
+	for _, mode := range []BWMode{BWModeUnlimited, BWModeAbsolute} {
+		if _, err := maxBandwidthOptions(mode, 8000000, 25); err == nil {
+			t.Errorf("overhead should be rejected in %s mode", mode)
+		}
+	}
+
+	for _, pct := range []int{4, 101} {
+		if _, err := maxBandwidthOptions(BWModeEstimated, 8000000, pct); err == nil {
+			t.Errorf("overhead %d%% should be rejected", pct)
+		}
+	}
+}
+
+func TestValidateBandwidthOptions(t *testing.T) {
+	cases := []struct {
+		options map[string]string
+		valid   bool
+	}{
+		{map[string]string{}, true},
+		{map[string]string{"maxbw": "-1"}, true},
+		{map[string]string{"maxbw": "1000000"}, true},
+		{map[string]string{"oheadbw": "25", "maxbw": "0", "inputbw": "1000000"}, true},
+		{map[string]string{"oheadbw": "25", "maxbw": "0", "mininputbw": "1000000"}, true},
+		{map[string]string{"oheadbw": "25"}, false},
+		{map[string]string{"oheadbw": "25", "maxbw": "-1"}, false},
+		{map[string]string{"oheadbw": "25", "maxbw": "1000000"}, false},
+		{map[string]string{"oheadbw": "25", "maxbw": "lots"}, false},
+	}
+	for _, c := range cases {
+		err := ValidateBandwidthOptions(c.options)
+		if c.valid && err != nil {
+			t.Errorf("%v should be valid, got %v", c.options, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%v should be rejected", c.options)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package srtgo
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+
+import (
+	"fmt"
+)
+
+// IdleTimeoutFunc is called with the handle of a socket whose connection broke
+type IdleTimeoutFunc func(socket *SrtSocket)
+
+// SetIdleTimeoutCallback registers fn to be called once when the connection
+// is declared dead, typically because the peer stayed silent for longer than
+// peeridletimeo, so that per-connection resources can be released without
+// waiting for the next Read to fail. The poll loop detects the break from the
+// SRT_EPOLL_ERR event, so the callback is only available for non-blocking
+// sockets. fn runs on its own goroutine and receives a copy of the handle the
+// callback was set on, so closing it closes the socket like Close on s.
+//
+// SRT does not report why a connection broke: an idle timeout, a peer that
+// closed the connection and a peer that vanished without notice all move the
+// socket to the broken state the same way, so idle timeouts cannot be told
+// apart and fn is called for every break of the connection. Closing the
+// socket locally does not call fn. A nil fn removes the callback. The callback
+// is released on Close.
+func (s SrtSocket) SetIdleTimeoutCallback(fn IdleTimeoutFunc) error {
+	if s.pd == nil {
+		return fmt.Errorf("idle timeout callback requires a non-blocking socket")
+	}
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if fn == nil {
+		s.state.onIdleTimeout = nil
+		s.state.idleTimeoutHandle = nil
+		return nil
+	}
+	handle := s
+	s.state.onIdleTimeout = fn
+	s.state.idleTimeoutHandle = &handle
+	return nil
+}
+
+// notifyBroken calls the break callback of a socket the poll loop reported
+// with SRT_EPOLL_ERR, if the connection broke rather than being closed
+func notifyBroken(pd *pollDesc) {
+	pd.lock.Lock()
+	st, socket := pd.state, pd.fd
+	pd.lock.Unlock()
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	fn, handle := st.onIdleTimeout, st.idleTimeoutHandle
+	if fn != nil && C.srt_getsockstate(socket) == C.SRTS_BROKEN {
+		st.onIdleTimeout = nil
+		st.idleTimeoutHandle = nil
+	} else {
+		fn = nil
+	}
+	st.mu.Unlock()
+	if fn != nil {
+		go fn(handle)
+	}
+}
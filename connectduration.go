@@ -0,0 +1,48 @@
+package srtgo
+
+import (
+	"time"
+)
+
+func (st *socketState) setConnectDuration(d time.Duration) {
+	st.mu.Lock()
+	st.connectDuration = d
+	st.mu.Unlock()
+}
+
+// acceptedConnectDuration sets the connect duration of a socket returned by
+// Accept, from the admission by the listen callback if the socket has pending
+// state, otherwise from the creation of the socket by SRT
+func (s SrtSocket) acceptedConnectDuration(pending *socketState) {
+	if pending != nil {
+		s.state.setConnectDuration(time.Since(pending.handshakeStart))
+		return
+	}
+	if stats, err := s.stats(false); err == nil {
+		s.state.setConnectDuration(time.Duration(stats.MsTimeStamp) * time.Millisecond)
+	}
+}
+
+// ConnectDuration returns how long establishing the connection took. For a
+// caller this is the time from the start of Connect, including the address
+// resolution, to the completed handshake.
+//
+// For an accepted socket it measures something else. The listener completes
+// its side of the handshake right after the listen callback admits the
+// connection, and SRT reports no event for that, so the value runs from the
+// admission by the listen callback to Accept returning the socket: it is
+// mostly the time the connection waited in the accept queue, not the
+// handshake. Without a listen callback it is measured from the creation of
+// the socket by SRT on arrival of the handshake, with millisecond resolution,
+// as reported in the MsTimeStamp statistic.
+//
+// The value is only meaningful after a successful Connect or Accept; it is 0
+// otherwise, e.g. while connecting or after a failed attempt.
+func (s SrtSocket) ConnectDuration() time.Duration {
+	if s.state == nil {
+		return 0
+	}
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	return s.state.connectDuration
+}
@@ -6,6 +6,8 @@ package srtgo
 */
 import "C"
 import (
+	"errors"
+	"os"
 	"runtime"
 	"strconv"
 	"syscall"
@@ -18,6 +20,7 @@ type SrtConnectionRejected struct{}
 type SrtConnectTimeout struct{}
 type SrtSocketClosed struct{}
 type SrtEpollTimeout struct{}
+type SrtWriteClosed struct{}
 
 func (m *SrtInvalidSock) Error() string {
 	return "Socket u indicates no valid socket ID"
@@ -43,6 +46,10 @@ func (m *SrtSocketClosed) Error() string {
 	return "The socket has been closed"
 }
 
+func (m *SrtWriteClosed) Error() string {
+	return "The socket has been closed for writing"
+}
+
 func (m *SrtEpollTimeout) Error() string {
 	return "Operation has timed out"
 }
@@ -55,6 +62,58 @@ func (m *SrtEpollTimeout) Temporary() bool {
 	return true
 }
 
+// Is makes an expired read/write deadline match os.ErrDeadlineExceeded, as
+// returned by net.Conn implementations of the standard library
+func (m *SrtEpollTimeout) Is(target error) bool {
+	return target == os.ErrDeadlineExceeded
+}
+
+// EncryptionFault tells which side of a connection caused an encryption rejection
+type EncryptionFault int
+
+const (
+	// EncryptionFaultLocal - the peer requires encryption but no passphrase is set locally
+	EncryptionFaultLocal EncryptionFault = iota
+	// EncryptionFaultPeer - a passphrase is set locally but the peer has none
+	EncryptionFaultPeer
+	// EncryptionFaultMismatch - both sides have a passphrase but they differ
+	EncryptionFaultMismatch
+)
+
+// String returns human-readable encryption fault name
+func (f EncryptionFault) String() string {
+	switch f {
+	case EncryptionFaultLocal:
+		return "local passphrase missing"
+	case EncryptionFaultPeer:
+		return "peer passphrase missing"
+	case EncryptionFaultMismatch:
+		return "passphrase mismatch"
+	default:
+		return "unknown"
+	}
+}
+
+// EncryptionError is returned by Connect when the handshake was rejected because
+// of the encryption configuration (SRT_REJ_UNSECURE or SRT_REJ_BADSECRET), which
+// typically happens with enforcedencryption enabled on either side.
+// A listener never sees these connections: they are rejected during the
+// handshake, before reaching Accept.
+type EncryptionError struct {
+	Reason  int             // SRT_REJ_UNSECURE or SRT_REJ_BADSECRET
+	Fault   EncryptionFault // which side is misconfigured
+	KmState SrtKmState      // local key material state when the rejection happened
+	Err     error           // underlying connection error
+}
+
+func (e *EncryptionError) Error() string {
+	return "Connection rejected due to encryption: " + e.Fault.String()
+}
+
+func (e *EncryptionError) Unwrap() error {
+	return e.Err
+}
+
 //MUST be called from same OS thread that generated the error (i.e.: use runtime.LockOSThread())
 func srtGetAndClearError() error {
 	defer C.srt_clearlasterror()
@@ -106,6 +165,12 @@ func (e SRTErrno) Error() string {
 }
 
 func (e SRTErrno) Is(target error) bool {
+	switch t := target.(type) {
+	case SRTErrno:
+		return e == t
+	case SRTErrorCategory:
+		return e != Unknown && e != Success && e.Category() == t
+	}
 	//for backwards compat
 	switch target.(type) {
 	case *SrtInvalidSock:
@@ -124,6 +189,11 @@ func (e SRTErrno) Is(target error) bool {
 	return false
 }
 
+// Category returns the category (major code) of the error
+func (e SRTErrno) Category() SRTErrorCategory {
+	return SRTErrorCategory(int(e) / srtMajorCodeFactor)
+}
+
 func (e SRTErrno) Temporary() bool {
 	return e == EAsyncFAIL || e == EAsyncRCV || e == EAsyncSND || e == ECongest || e == ETimeout
 }
@@ -164,6 +234,55 @@ func (e *srtErrnoSysErrnoWrapped) Unwrap() error {
 	return error(e.eSys)
 }
 
+// srtErrnoOf extracts the SRTErrno from err, looking through wrapping
+func srtErrnoOf(err error) (SRTErrno, bool) {
+	var wrapped *srtErrnoSysErrnoWrapped
+	if errors.As(err, &wrapped) {
+		return wrapped.e, true
+	}
+	var errno SRTErrno
+	if errors.As(err, &errno) {
+		return errno, true
+	}
+	return 0, false
+}
+
+// IsTimeout reports whether err is a timeout: an expired read/write deadline
+// or an SRT operation that timed out (ETimeout, e.g. rcvtimeo/sndtimeo).
+// EAsyncRCV and EAsyncSND only mean that the operation would block in
+// non-blocking mode; they are not timeouts and IsTimeout reports false for them.
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errno, ok := srtErrnoOf(err); ok {
+		return errno.Timeout()
+	}
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
+
+// IsConnectionBroken reports whether err means that the connection is no
+// longer usable: it was lost, closed, never established or the socket is gone.
+func IsConnectionBroken(err error) bool {
+	if err == nil {
+		return false
+	}
+	var closed *SrtSocketClosed
+	if errors.As(err, &closed) {
+		return true
+	}
+	errno, ok := srtErrnoOf(err)
+	if !ok {
+		return false
+	}
+	switch errno {
+	case EConnLost, EConnFail, ENoConn, ESClosed, EInvSock:
+		return true
+	}
+	return false
+}
+
 //Shadows SRT_ERRNO srtcore/srt.h line 490+
 const (
 	Unknown = SRTErrno(C.SRT_EUNKNOWN)
@@ -217,6 +336,102 @@ const (
 	EPeer = SRTErrno(C.SRT_EPEERERR)
 )
 
+// Err-prefixed names of the SRT error codes, following the Go convention for
+// sentinel errors, e.g. errors.Is(err, srtgo.ErrConnLost). They are the same
+// values as the E-prefixed constants above.
+const (
+	// Setup: the connection could not be established
+	ErrConnSetup = EConnSetup // general setup error
+	ErrNoServer  = ENoServer  // connection timed out, no response from the remote address
+	ErrConnRej   = EConnRej   // connection rejected by the peer, see RejectReason
+	ErrSockFail  = ESockFail  // system call on the UDP socket failed
+	ErrSecFail   = ESecFail   // handshake tampering detected or encryption not fulfilled
+	ErrSClosed   = ESClosed   // socket closed during a blocking operation
+
+	// Connection: an established connection failed
+	ErrConnFail = EConnFail // general connection failure
+	ErrConnLost = EConnLost // the connection has been broken
+	ErrNoConn   = ENoConn   // the socket is not connected
+
+	// System resources
+	ErrResource = EResource // unexpected system or library error
+	ErrThread   = EThread   // a thread could not be spawned
+	ErrNoBuf    = EnoBuf    // buffer memory could not be allocated
+	ErrSysObj   = ESysObj   // system objects could not be allocated
+
+	// Filesystem (SendFile/RecvFile)
+	ErrFile     = EFile     // general filesystem error
+	ErrInvRdOff = EInvRdOff // cannot read from the given file position
+	ErrRdPerm   = ERdPerm   // read permission denied
+	ErrInvWrOff = EInvWrOff // cannot seek in the written file
+	ErrWrPerm   = EWrPerm   // write permission denied
+
+	// Not supported: invalid use of the API for the socket state
+	ErrInvOp          = EInvOp          // invalid operation for the socket state
+	ErrBoundSock      = EBoundSock      // the socket is already bound
+	ErrConnSock       = EConnSock       // the socket is already connected
+	ErrInvParam       = EInvParam       // invalid call parameters
+	ErrInvSock        = EInvSock        // invalid socket or group id
+	ErrUnboundSock    = EUnboundSock    // the socket must be bound first
+	ErrNoListen       = ENoListen       // the socket is not listening
+	ErrRdvNoServ      = ERdvNoServ      // not possible in rendezvous mode
+	ErrRdvUnbound     = ERdvUnbound     // rendezvous socket not bound
+	ErrInvalMsgAPI    = EInvalMsgAPI    // invalid use of the message API
+	ErrInvalBufferAPI = EInvalBufferAPI // invalid use of the stream API
+	ErrDupListen      = EDupListen      // the port is already used by a listener
+	ErrLargeMsg       = ELargeMsg       // message too large
+	ErrInvPollID      = EInvPollID      // invalid epoll id
+	ErrPollEmpty      = EPollEmpty      // no sockets subscribed to the epoll
+
+	// Again: the operation could not be completed now
+	ErrAsyncFail = EAsyncFAIL // general asynchronous failure
+	ErrAsyncSnd  = EAsyncSND  // sending would block (non-blocking mode)
+	ErrAsyncRcv  = EAsyncRCV  // receiving would block (non-blocking mode)
+	ErrTimeout   = ETimeout   // the operation timed out
+	ErrCongest   = ECongest   // packets dropped by the sender (too late)
+
+	// Peer
+	ErrPeer = EPeer // the peer reported an error
+)
+
+// SRT error codes are major*1000 + minor
+const srtMajorCodeFactor = 1000
+
+// SRTErrorCategory groups SRT error codes by their major code. Every SRTErrno
+// matches its category with errors.Is, e.g. errors.Is(err, srtgo.ErrCategoryConnection).
+type SRTErrorCategory int
+
+//Shadows CodeMajor srtcore/srt.h
+const (
+	ErrCategorySetup          = SRTErrorCategory(C.MJ_SETUP)
+	ErrCategoryConnection     = SRTErrorCategory(C.MJ_CONNECTION)
+	ErrCategorySystemResource = SRTErrorCategory(C.MJ_SYSTEMRES)
+	ErrCategoryFilesystem     = SRTErrorCategory(C.MJ_FILESYSTEM)
+	ErrCategoryNotSupported   = SRTErrorCategory(C.MJ_NOTSUP)
+	ErrCategoryAgain          = SRTErrorCategory(C.MJ_AGAIN)
+	ErrCategoryPeer           = SRTErrorCategory(C.MJ_PEERERROR)
+)
+
+func (c SRTErrorCategory) Error() string {
+	switch c {
+	case ErrCategorySetup:
+		return "srt connection setup error"
+	case ErrCategoryConnection:
+		return "srt connection error"
+	case ErrCategorySystemResource:
+		return "srt system resource error"
+	case ErrCategoryFilesystem:
+		return "srt filesystem error"
+	case ErrCategoryNotSupported:
+		return "srt operation not supported"
+	case ErrCategoryAgain:
+		return "srt operation not ready"
+	case ErrCategoryPeer:
+		return "srt peer error"
+	}
+	return "srt error category: " + strconv.Itoa(int(c))
+}
+
 //Unknown cannot be here since it would have a negative index!
 //Error strings taken from: https://github.com/Haivision/srt/blob/master/docs/API/API-functions.md
 var srterrors = [...]string{
@@ -0,0 +1,104 @@
+package srtgo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsTimeout(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&SrtEpollTimeout{}, true},
+		{ETimeout, true},
+		{ETimeout.wrapSysErr(syscall.EAGAIN), true},
+		{fmt.Errorf("read: %w", ETimeout), true},
+		{EAsyncRCV, false},
+		{EAsyncSND, false},
+		{EConnLost, false},
+	}
+	for _, c := range cases {
+		if got := IsTimeout(c.err); got != c.expected {
+			t.Errorf("IsTimeout(%v) = %t, expected %t", c.err, got, c.expected)
+		}
+	}
+}
+
+func TestIsConnectionBroken(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&SrtSocketClosed{}, true},
+		{EConnLost, true},
+		{ENoConn, true},
+		{EConnLost.wrapSysErr(syscall.ECONNRESET), true},
+		{fmt.Errorf("write: %w", ESClosed), true},
+		{EAsyncRCV, false},
+		{&SrtEpollTimeout{}, false},
+	}
+	for _, c := range cases {
+		if got := IsConnectionBroken(c.err); got != c.expected {
+			t.Errorf("IsConnectionBroken(%v) = %t, expected %t", c.err, got, c.expected)
+		}
+	}
+}
+
+func TestEpollTimeoutIsDeadlineExceeded(t *testing.T) {
+	var err error = &SrtEpollTimeout{}
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Error("SrtEpollTimeout should match os.ErrDeadlineExceeded")
+	}
+	if !errors.Is(fmt.Errorf("read: %w", err), os.ErrDeadlineExceeded) {
+		t.Error("wrapped SrtEpollTimeout should match os.ErrDeadlineExceeded")
+	}
+	if errors.Is(ETimeout, os.ErrDeadlineExceeded) {
+		t.Error("ETimeout is not a deadline expiry")
+	}
+}
+
+func TestSRTErrnoIs(t *testing.T) {
+	wrapped := EConnLost.wrapSysErr(syscall.ECONNRESET)
+	if !errors.Is(wrapped, ErrConnLost) {
+		t.Error("errno wrapping a system error should match its code")
+	}
+	if !errors.Is(fmt.Errorf("read: %w", wrapped), EConnLost) {
+		t.Error("wrapped errno should match its code")
+	}
+	if errors.Is(wrapped, ErrConnFail) {
+		t.Error("errno should not match another code")
+	}
+	if !errors.Is(wrapped, syscall.ECONNRESET) {
+		t.Error("errno wrapping a system error should match the system error")
+	}
+
+	cases := []struct {
+		err      SRTErrno
+		category SRTErrorCategory
+	}{
+		{ErrConnRej, ErrCategorySetup},
+		{ErrConnLost, ErrCategoryConnection},
+		{ErrNoBuf, ErrCategorySystemResource},
+		{ErrWrPerm, ErrCategoryFilesystem},
+		{ErrLargeMsg, ErrCategoryNotSupported},
+		{ErrAsyncRcv, ErrCategoryAgain},
+		{ErrPeer, ErrCategoryPeer},
+	}
+	for _, c := range cases {
+		if !errors.Is(c.err, c.category) {
+			t.Errorf("%v should match %v", c.err, c.category)
+		}
+		if errors.Is(c.err, ErrCategoryFilesystem) != (c.category == ErrCategoryFilesystem) {
+			t.Errorf("%v matched the wrong category", c.err)
+		}
+	}
+	if errors.Is(Success, ErrCategoryConnection) || errors.Is(Unknown, ErrCategorySetup) {
+		t.Error("Success and Unknown belong to no category")
+	}
+}
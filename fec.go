@@ -0,0 +1,126 @@
+package srtgo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FEC layouts accepted by SRT's built-in "fec" packet filter
+const (
+	FECLayoutEven      = "even"
+	FECLayoutStaircase = "staircase"
+)
+
+// FEC ARQ modes accepted by SRT's built-in "fec" packet filter
+const (
+	FECARQAlways = "always"
+	FECARQOnReq  = "onreq"
+	FECARQNever  = "never"
+)
+
+const fecFilterName = "fec"
+
+// Minimum number of columns accepted by SRT for the "fec" filter
+const minFECCols = 2
+
+// FECConfig describes the built-in "fec" packet filter (packetfilter option).
+// Zero values of Layout and ARQ are left out of the configuration string, so
+// SRT applies its defaults (even layout, onreq ARQ).
+type FECConfig struct {
+	Cols   int    // number of packets in a row group, at least 2
+	Rows   int    // number of packets in a column group, at least 1; 1 means row FEC only
+	Layout string // FECLayoutEven or FECLayoutStaircase
+	ARQ    string // FECARQAlways, FECARQOnReq or FECARQNever
+}
+
+// Validate checks the configuration against the constraints of SRT's "fec" filter
+func (c FECConfig) Validate() error {
+	if c.Cols < minFECCols {
+		return fmt.Errorf("invalid FEC cols %d (must be at least %d)", c.Cols, minFECCols)
+	}
+	if c.Rows < 1 {
+		return fmt.Errorf("invalid FEC rows %d (must be at least 1)", c.Rows)
+	}
+	switch c.Layout {
+	case "", FECLayoutEven, FECLayoutStaircase:
+	default:
+		return fmt.Errorf("invalid FEC layout %q (must be %q or %q)", c.Layout, FECLayoutEven, FECLayoutStaircase)
+	}
+	switch c.ARQ {
+	case "", FECARQAlways, FECARQOnReq, FECARQNever:
+	default:
+		return fmt.Errorf("invalid FEC arq %q (must be %q, %q or %q)", c.ARQ, FECARQAlways, FECARQOnReq, FECARQNever)
+	}
+	return nil
+}
+
+// String returns the packetfilter option value, e.g. "fec,cols:10,rows:5,layout:staircase"
+func (c FECConfig) String() string {
+	parts := []string{fecFilterName, "cols:" + strconv.Itoa(c.Cols), "rows:" + strconv.Itoa(c.Rows)}
+	if c.Layout != "" {
+		parts = append(parts, "layout:"+c.Layout)
+	}
+	if c.ARQ != "" {
+		parts = append(parts, "arq:"+c.ARQ)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseFECConfig parses a packetfilter option value of the "fec" filter. A
+// missing rows parameter means 1, as in SRT.
+func ParseFECConfig(filter string) (FECConfig, error) {
+	c := FECConfig{Rows: 1}
+	parts := strings.Split(filter, ",")
+	if parts[0] != fecFilterName {
+		return c, fmt.Errorf("not a FEC filter: %q", filter)
+	}
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) != 2 {
+			return FECConfig{}, fmt.Errorf("invalid FEC parameter %q", part)
+		}
+		var err error
+		switch kv[0] {
+		case "cols":
+			c.Cols, err = strconv.Atoi(kv[1])
+		case "rows":
+			c.Rows, err = strconv.Atoi(kv[1])
+		case "layout":
+			c.Layout = kv[1]
+		case "arq":
+			c.ARQ = kv[1]
+		default:
+			return FECConfig{}, fmt.Errorf("unknown FEC parameter %q", kv[0])
+		}
+		if err != nil {
+			return FECConfig{}, fmt.Errorf("invalid FEC %s value %q", kv[0], kv[1])
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return FECConfig{}, err
+	}
+	return c, nil
+}
+
+// validatePacketFilter checks "fec" filter configurations; other filters,
+// including ones whose name merely starts with "fec", are passed to SRT
+// unchecked
+func validatePacketFilter(val string) error {
+	if val != fecFilterName && !strings.HasPrefix(val, fecFilterName+",") {
+		return nil
+	}
+	_, err := ParseFECConfig(val)
+	return err
+}
+
+// SetFEC enables forward error correction with the given configuration.
+// packetfilter is a PRE option, so this must be called before connecting.
+// Both peers must configure the filter, or at least one of them when the
+// other accepts the configuration proposed during the handshake.
+func (s SrtSocket) SetFEC(cfg FECConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	return s.setOption("packetfilter", cfg.String())
+}
@@ -0,0 +1,58 @@
+package srtgo
+
+import "testing"
+
+func TestFECConfigString(t *testing.T) {
+	cfg := FECConfig{Cols: 10, Rows: 5, Layout: FECLayoutStaircase}
+	if s := cfg.String(); s != "fec,cols:10,rows:5,layout:staircase" {
+		t.Errorf("unexpected filter string %q", s)
+	}
+	parsed, err := ParseFECConfig(cfg.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != cfg {
+		t.Errorf("round trip mismatch: expected %+v, got %+v", cfg, parsed)
+	}
+}
+
+func TestFECConfigValidate(t *testing.T) {
+	invalid := []FECConfig{
+		{Cols: 1},
+		{Cols: 10, Rows: -1},
+		{Cols: 10},
+		{Cols: 10, Layout: "diagonal"},
+		{Cols: 10, ARQ: "sometimes"},
+	}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v should be rejected", cfg)
+		}
+	}
+
+	for _, filter := range []string{"fec", "fec,cols:x", "fec,cols:10,foo:1", "fec,cols"} {
+		if _, err := ParseFECConfig(filter); err == nil {
+			t.Errorf("%q should be rejected", filter)
+		}
+	}
+
+	parsed, err := ParseFECConfig("fec,cols:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Rows != 1 {
+		t.Errorf("expected the default of 1 row, got %d", parsed.Rows)
+	}
+
+	for _, filter := range []string{"fec", "fec,cols:1"} {
+		err := ValidateSocketOptionsForLifecycle(LifecyclePre, map[string]string{"packetfilter": filter})
+		if err == nil {
+			t.Errorf("packetfilter %q with invalid FEC config should be rejected", filter)
+		}
+	}
+	// Other filters are left to SRT, even if their name starts with "fec"
+	err = ValidateSocketOptionsForLifecycle(LifecyclePre, map[string]string{"packetfilter": "fecx,cols:1"})
+	if err != nil {
+		t.Errorf("packetfilter of another filter should not be checked, got %v", err)
+	}
+}
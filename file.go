@@ -0,0 +1,109 @@
+package srtgo
+
+/*
+#cgo LDFLAGS: -lsrt
+#include <stdlib.h>
+#include <srt/srt.h>
+
+int64_t srt_sendfile_wrapped(SRTSOCKET u, const char* path, int64_t* offset, int64_t size, int *srterror, int *syserror)
+{
+	int64_t ret = srt_sendfile(u, path, offset, size, SRT_DEFAULT_SENDFILE_BLOCK);
+	if (ret < 0) {
+		*srterror = srt_getlasterror(syserror);
+	}
+	return ret;
+}
+
+int64_t srt_recvfile_wrapped(SRTSOCKET u, const char* path, int64_t* offset, int64_t size, int *srterror, int *syserror)
+{
+	int64_t ret = srt_recvfile(u, path, offset, size, SRT_DEFAULT_RECVFILE_BLOCK);
+	if (ret < 0) {
+		*srterror = srt_getlasterror(syserror);
+	}
+	return ret;
+}
+
+*/
+import "C"
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// checkFileMode returns an error unless the socket uses file mode with the
+// stream API, which srt_sendfile and srt_recvfile require. transtype cannot be
+// read back, so file mode is recognized by the file congestion controller it
+// selects.
+func (s SrtSocket) checkFileMode() error {
+	congestion, err := s.GetSockOptString(SRTO_CONGESTION)
+	if err != nil {
+		return err
+	}
+	if congestion != "file" {
+		return fmt.Errorf("file transfer requires transtype 'file'")
+	}
+	messageAPI, err := s.MessageAPI()
+	if err != nil {
+		return err
+	}
+	if messageAPI {
+		return fmt.Errorf("file transfer requires the stream API (messageapi 0)")
+	}
+	return nil
+}
+
+func srtFileErr(srterr, syserr C.int) error {
+	srterror := SRTErrno(srterr)
+	if syserr < 0 {
+		return srterror.wrapSysErr(syscall.Errno(syserr))
+	}
+	return srterror
+}
+
+// SendFile sends size bytes of the file at path, starting at offset, using
+// srt_sendfile. The socket must use the file transtype with the message API
+// disabled. The call blocks the
+// calling goroutine until the data has been handed to SRT, regardless of the
+// blocking option. Returns the number of bytes sent.
+func (s SrtSocket) SendFile(path string, offset int64, size int64) (int64, error) {
+	if err := s.checkFileMode(); err != nil {
+		return 0, err
+	}
+	if err := s.checkWriteClosed(); err != nil {
+		return 0, err
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	srterr := C.int(0)
+	syserr := C.int(0)
+	off := C.int64_t(offset)
+	n := C.srt_sendfile_wrapped(s.socket, cpath, &off, C.int64_t(size), &srterr, &syserr)
+	if n < 0 {
+		return 0, fmt.Errorf("Error in srt_sendfile: %w", srtFileErr(srterr, syserr))
+	}
+	return int64(n), nil
+}
+
+// RecvFile receives size bytes into the file at path, writing from offset,
+// using srt_recvfile. The socket must use the file transtype with the message
+// API disabled. The call blocks the calling goroutine until size bytes have
+// been received, regardless of the blocking option. Returns the number of
+// bytes received.
+func (s SrtSocket) RecvFile(path string, offset int64, size int64) (int64, error) {
+	if err := s.checkFileMode(); err != nil {
+		return 0, err
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	srterr := C.int(0)
+	syserr := C.int(0)
+	off := C.int64_t(offset)
+	n := C.srt_recvfile_wrapped(s.socket, cpath, &off, C.int64_t(size), &srterr, &syserr)
+	if n < 0 {
+		return 0, fmt.Errorf("Error in srt_recvfile: %w", srtFileErr(srterr, syserr))
+	}
+	return int64(n), nil
+}
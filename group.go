@@ -0,0 +1,184 @@
+package srtgo
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GroupMemberState is the state of a member link of a socket group
+type GroupMemberState int
+
+const (
+	// GroupMemberPending - the link is still connecting
+	GroupMemberPending GroupMemberState = GroupMemberState(C.SRT_GST_PENDING)
+	// GroupMemberIdle - the link is connected but not used for sending,
+	// e.g. a backup link
+	GroupMemberIdle GroupMemberState = GroupMemberState(C.SRT_GST_IDLE)
+	// GroupMemberRunning - the link is used for sending
+	GroupMemberRunning GroupMemberState = GroupMemberState(C.SRT_GST_RUNNING)
+	// GroupMemberBroken - the link broke and is about to be removed
+	GroupMemberBroken GroupMemberState = GroupMemberState(C.SRT_GST_BROKEN)
+)
+
+// String returns human-readable member state name
+func (m GroupMemberState) String() string {
+	switch m {
+	case GroupMemberPending:
+		return "pending"
+	case GroupMemberIdle:
+		return "idle"
+	case GroupMemberRunning:
+		return "running"
+	case GroupMemberBroken:
+		return "broken"
+	default:
+		return "unknown"
+	}
+}
+
+// GroupMemberStats holds the statistics of one member link of a socket group
+type GroupMemberStats struct {
+	Socket int              // SRT socket id of the member
+	Token  int              // token of the link, as reported to ConnectCallbackFunc
+	Weight int              // weight (backup mode priority) of the link
+	State  GroupMemberState // member state, e.g. running or idle in backup mode
+	Stats  *SrtStats        // link statistics, nil if they could not be read
+}
+
+// GroupStats holds the statistics of a socket group and of its member links
+type GroupStats struct {
+	Group   *SrtStats // aggregate statistics of the group
+	Members []GroupMemberStats
+}
+
+// defaultGroupMembers is the initial capacity used to query group members
+const defaultGroupMembers = 8
+
+// GroupMember identifies a member link of a socket group
+type GroupMember struct {
+	Socket int              // SRT socket id of the member
+	Token  int              // token of the link, as reported to ConnectCallbackFunc
+	Weight int              // weight (backup mode priority) of the link
+	State  GroupMemberState // member state, e.g. running or idle in backup mode
+}
+
+// GroupMembers returns the member links of a socket group, e.g. of a group
+// returned by Accept on a listener with groupconnect enabled. Unlike
+// GroupStats it does not read the statistics of every member. Only available
+// on group sockets, see IsGroup.
+func (s SrtSocket) GroupMembers() ([]GroupMember, error) {
+	if !s.IsGroup() {
+		return nil, errors.New("GroupMembers requires a socket group")
+	}
+	data, err := s.groupData()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]GroupMember, 0, len(data))
+	for _, m := range data {
+		members = append(members, GroupMember{
+			Socket: int(m.id),
+			Token:  int(m.token),
+			Weight: int(m.weight),
+			State:  GroupMemberState(m.memberstate),
+		})
+	}
+	return members, nil
+}
+
+// GroupID returns the id of the socket group the socket is a member of, e.g.
+// for a socket passed to a ConnectCallbackFunc. It fails for a socket that is
+// not a member of a group, including a member that has not completed its
+// handshake yet, as in the listen callback.
+func (s SrtSocket) GroupID() (int, error) {
+	group := C.srt_groupof(s.socket)
+	if group == SRT_INVALID_SOCK {
+		return 0, fmt.Errorf("socket is not a group member: %w", srtGetAndClearErrorThreadSafe())
+	}
+	return int(group), nil
+}
+
+// IsGroup reports whether the socket is a socket group (bonding) rather than a
+// single connection, e.g. one accepted on a listener with groupconnect set
+func (s SrtSocket) IsGroup() bool {
+	return s.socket&C.SRTGROUP_MASK != 0
+}
+
+// GroupStats returns the aggregate statistics of a socket group together with
+// the statistics of each member link, labelled with its token, weight and
+// state, e.g. to see which link is active in backup mode. The interval
+// counters are not reset, as with StatsNoClear. Members whose statistics
+// cannot be read, e.g. a link that broke meanwhile, are reported with nil
+// Stats. Only available on group sockets, see IsGroup; requires an SRT library
+// built with bonding support.
+func (s SrtSocket) GroupStats() (*GroupStats, error) {
+	if !s.IsGroup() {
+		return nil, errors.New("GroupStats requires a socket group")
+	}
+	members, err := s.groupData()
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.stats(false)
+	if err != nil {
+		return nil, err
+	}
+
+	gs := &GroupStats{Group: group, Members: make([]GroupMemberStats, 0, len(members))}
+	for _, m := range members {
+		member := SrtSocket{socket: m.id}
+		stats, _ := member.stats(false)
+		gs.Members = append(gs.Members, GroupMemberStats{
+			Socket: int(m.id),
+			Token:  int(m.token),
+			Weight: int(m.weight),
+			State:  GroupMemberState(m.memberstate),
+			Stats:  stats,
+		})
+	}
+	return gs, nil
+}
+
+// MemberWeights returns the weight of every member link of a socket group,
+// keyed by link token. In backup mode the weight is the priority of a link:
+// the one with the highest weight is activated first on failover.
+//
+// SRT assigns the weight when a member connects (SRT_SOCKGROUPCONFIG) and has
+// no call to change it on an established link, so there is no setter; to
+// reprioritize a link it has to be reconnected with the new weight.
+func (s SrtSocket) MemberWeights() (map[int]int, error) {
+	if !s.IsGroup() {
+		return nil, errors.New("MemberWeights requires a socket group")
+	}
+	members, err := s.groupData()
+	if err != nil {
+		return nil, err
+	}
+	weights := make(map[int]int, len(members))
+	for _, m := range members {
+		weights[int(m.token)] = int(m.weight)
+	}
+	return weights, nil
+}
+
+// groupData returns the member data of a socket group, growing the buffer
+// until all members fit
+func (s SrtSocket) groupData() ([]C.SRT_SOCKGROUPDATA, error) {
+	data := make([]C.SRT_SOCKGROUPDATA, defaultGroupMembers)
+	for {
+		size := C.size_t(len(data))
+		n := C.srt_group_data(s.socket, &data[0], &size)
+		if n != SRT_ERROR {
+			return data[:n], nil
+		}
+		err := srtGetAndClearErrorThreadSafe()
+		if int(size) <= len(data) {
+			return nil, fmt.Errorf("Error getting group data, %w", err)
+		}
+		data = make([]C.SRT_SOCKGROUPDATA, size)
+	}
+}
@@ -0,0 +1,309 @@
+package srtgo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// messageLimits holds the sizes of the data a single Read or Write handles on
+// a connection, see messageLimits
+type messageLimits struct {
+	// payload is what one packet carries: the negotiated payloadsize in live
+	// mode, otherwise derived from the MSS
+	payload int
+	// max is the largest message in message mode, 0 in stream mode where
+	// reads and writes have no message bound
+	max int
+	// read is a buffer size that holds whatever a single Read delivers: max
+	// in message mode, payload in stream mode
+	read int
+	// live is set in live mode, where a message is a single packet
+	live bool
+}
+
+// messageLimits returns the message sizes of the connection. A message is a
+// single payload in live mode. In file message mode it may span many packets,
+// up to the flow control window (the fc option, in packets) of full payloads,
+// and it must fit the local send and receive buffers; with the same settings
+// on both peers this bounds both directions. If the sizes cannot be read,
+// e.g. before the connection is established, the packet size is used as
+// payload and read size together with the error.
+func (s SrtSocket) messageLimits() (messageLimits, error) {
+	fallback := messageLimits{payload: s.pktSize, read: s.pktSize}
+	payload, err := s.MaxPayloadSize()
+	if err != nil {
+		return fallback, err
+	}
+	l := messageLimits{payload: payload, read: payload}
+	if live, err := s.GetSockOptInt(SRTO_PAYLOADSIZE); err != nil {
+		return fallback, err
+	} else if live > 0 {
+		l.max = payload
+		l.live = true
+		return l, nil
+	}
+	messageAPI, err := s.GetSockOptBool(SRTO_MESSAGEAPI)
+	if err != nil {
+		return fallback, err
+	}
+	if !messageAPI {
+		return l, nil
+	}
+	fc, err := s.GetSockOptInt(SRTO_FC)
+	if err != nil {
+		return fallback, err
+	}
+	l.max = fc * payload
+	for _, opt := range []int{SRTO_SNDBUF, SRTO_RCVBUF} {
+		size, err := s.GetSockOptInt(opt)
+		if err != nil {
+			return fallback, err
+		}
+		if size < l.max {
+			l.max = size
+		}
+	}
+	l.read = l.max
+	return l, nil
+}
+
+// readBufferSize returns a buffer size large enough to hold whatever a single
+// Read delivers, see messageLimits
+func (s SrtSocket) readBufferSize() int {
+	l, _ := s.messageLimits()
+	return l.read
+}
+
+// messageBufferPool recycles scratch buffers that hold a whole message, which
+// can be large in file message mode
+var messageBufferPool sync.Pool
+
+// getMessageBuffer returns a scratch buffer of size bytes from
+// messageBufferPool, to be returned with messageBufferPool.Put
+func getMessageBuffer(size int) *[]byte {
+	if bp, ok := messageBufferPool.Get().(*[]byte); ok && cap(*bp) >= size {
+		*bp = (*bp)[:size]
+		return bp
+	}
+	b := make([]byte, size)
+	return &b
+}
+
+// WriteTo implements io.WriterTo. It reads from the SRT socket until the peer
+// closes a stream mode connection cleanly, which is not an error, or an error
+// occurs, and writes every received message to w, reusing a single buffer sized
+// to the negotiated payload size. Deadlines set on the socket are honoured.
+func (s SrtSocket) WriteTo(w io.Writer) (n int64, err error) {
+	buf := make([]byte, s.readBufferSize())
+	for {
+		nr, rerr := s.Read(buf)
+		if nr > 0 {
+			nw, werr := w.Write(buf[:nr])
+			n += int64(nw)
+			if werr != nil {
+				return n, werr
+			}
+			if nw != nr {
+				return n, io.ErrShortWrite
+			}
+		}
+		if rerr != nil {
+			if rerr == io.EOF {
+				return n, nil
+			}
+			return n, rerr
+		}
+	}
+}
+
+// ReadFrom implements io.ReaderFrom. It reads from r until EOF and sends the
+// data over the SRT socket in chunks no larger than the payload of a packet.
+// Deadlines set on the socket are honoured.
+func (s SrtSocket) ReadFrom(r io.Reader) (n int64, err error) {
+	l, _ := s.messageLimits()
+	buf := make([]byte, l.payload)
+	for {
+		nr, rerr := r.Read(buf)
+		if nr > 0 {
+			written := 0
+			for written < nr {
+				nw, werr := s.Write(buf[written:nr])
+				written += nw
+				n += int64(nw)
+				if werr != nil {
+					return n, werr
+				}
+			}
+		}
+		if rerr != nil {
+			if rerr == io.EOF {
+				return n, nil
+			}
+			return n, rerr
+		}
+	}
+}
+
+// WriteString sends str as a single message like Write. The string is copied
+// into a pooled scratch buffer, the one WriteV uses, so no allocation is made
+// per call once the pool is warm.
+func (s SrtSocket) WriteString(str string) (int, error) {
+	if len(str) == 0 {
+		return 0, nil
+	}
+	bp := writevBufPool.Get().(*[]byte)
+	buf := append((*bp)[:0], str...)
+	n, err := s.Write(buf)
+	*bp = buf
+	writevBufPool.Put(bp)
+	return n, err
+}
+
+// ReadString reads a single message of at most max bytes and returns it as a
+// string. The read deadline of the socket applies. In stream mode the
+// returned string holds whatever was available, up to max bytes, and may be a
+// fragment of what the peer sent; in message mode it holds one whole message,
+// and a message longer than max is reported as an error by SRT.
+func (s SrtSocket) ReadString(max int) (string, error) {
+	if max <= 0 {
+		return "", nil
+	}
+	buf := make([]byte, max)
+	n, err := s.Read(buf)
+	return string(buf[:n]), err
+}
+
+// CopyN copies n bytes from src to dst, reusing a single buffer. If
+// perOpDeadline is positive, every individual read and write must complete
+// within it, so a stalled source or destination ends the copy with a timeout
+// error instead of hanging; the read deadline of src and the write deadline of
+// dst are cleared on return. Deadlines require non-blocking sockets, blocking
+// sockets can be bounded with SetReceiveTimeout/SetSendTimeout instead.
+// Returns the number of bytes actually copied, also on early termination.
+//
+// In stream mode exactly n bytes are copied. In message mode each read
+// delivers a whole message, so n should fall on a message boundary: a message
+// straddling it does not fit the shortened read buffer and fails.
+func CopyN(dst, src *SrtSocket, n int64, perOpDeadline time.Duration) (written int64, err error) {
+	if perOpDeadline > 0 {
+		if src.blocking || dst.writeBlocking {
+			return 0, errors.New("CopyN deadlines require non-blocking sockets")
+		}
+		defer src.SetReadDeadline(time.Time{})
+		defer dst.SetWriteDeadline(time.Time{})
+	}
+
+	buf := make([]byte, src.readBufferSize())
+	for written < n {
+		chunk := buf
+		if remaining := n - written; remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+
+		if perOpDeadline > 0 {
+			src.SetReadDeadline(time.Now().Add(perOpDeadline))
+		}
+		nr, rerr := src.Read(chunk)
+		if nr > 0 {
+			sent := 0
+			for sent < nr {
+				if perOpDeadline > 0 {
+					dst.SetWriteDeadline(time.Now().Add(perOpDeadline))
+				}
+				nw, werr := dst.Write(chunk[sent:nr])
+				sent += nw
+				written += int64(nw)
+				if werr != nil {
+					return written, werr
+				}
+			}
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+	return written, nil
+}
+
+// messageReader adapts Read to callers that pass buffers of arbitrary size,
+// like bufio.Scanner: in message mode a message larger than the buffer passed
+// to Read would be lost, so messages are read whole into a scratch buffer of
+// size bytes and handed out in as many pieces as the caller needs. The
+// scratch buffer is borrowed from messageBufferPool only while a message is
+// pending, so an idle reader holds none.
+type messageReader struct {
+	s       SrtSocket
+	size    int
+	scratch *[]byte
+	pending []byte
+}
+
+func (r *messageReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		// Read straight into p when any message fits, saving a copy
+		if len(p) >= r.size {
+			return r.s.Read(p)
+		}
+		r.scratch = getMessageBuffer(r.size)
+		n, err := r.s.Read(*r.scratch)
+		if err != nil {
+			r.release()
+			return 0, err
+		}
+		r.pending = (*r.scratch)[:n]
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	if len(r.pending) == 0 {
+		r.release()
+	}
+	return n, nil
+}
+
+// release returns the scratch buffer to the pool
+func (r *messageReader) release() {
+	if r.scratch != nil {
+		messageBufferPool.Put(r.scratch)
+		r.scratch = nil
+	}
+	r.pending = nil
+}
+
+// FramedReader returns a bufio.Scanner that splits the data received on the
+// socket into application frames with split, e.g. ScanTSPackets for MPEG-TS.
+// Frames are independent of SRT message boundaries: a frame may span several
+// messages and a message may carry several frames. Received messages are
+// copied once, into the buffer of the scanner, which grows up to
+// bufio.MaxScanTokenSize unless set otherwise with Scanner.Buffer.
+//
+// Scanning stops at the first read error, which Scanner.Err reports; the read
+// deadline of the socket applies to every underlying read. The socket must
+// not be read from by other means while the scanner is in use.
+func (s SrtSocket) FramedReader(split bufio.SplitFunc) *bufio.Scanner {
+	scanner := bufio.NewScanner(&messageReader{s: s, size: s.readBufferSize()})
+	scanner.Split(split)
+	return scanner
+}
+
+// TSPacketSize is the size of an MPEG transport stream packet
+const TSPacketSize = 188
+
+// errTruncatedTSPacket is returned by ScanTSPackets for trailing data that is
+// shorter than a packet
+var errTruncatedTSPacket = errors.New("truncated MPEG-TS packet")
+
+// ScanTSPackets is a split function for FramedReader that returns each
+// 188 byte MPEG-TS packet as a frame. It does not resynchronize on the sync
+// byte, as SRT delivers the stream without corruption.
+func ScanTSPackets(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) >= TSPacketSize {
+		return TSPacketSize, data[:TSPacketSize], nil
+	}
+	if atEOF && len(data) > 0 {
+		return 0, nil, errTruncatedTSPacket
+	}
+	return 0, nil, nil
+}
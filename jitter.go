@@ -0,0 +1,60 @@
+package srtgo
+
+import (
+	"time"
+)
+
+// SRT packet timestamps are 32 bit microsecond counters; srctime values from
+// libsrt versions that do not extend them wrap around after this period
+const srcTimeWrapUs = int64(1) << 32
+
+type jitterState struct {
+	srcTime int64
+	arrival time.Time
+}
+
+// JitterRead reads a message like Read and also returns the variation of its
+// transit time relative to the previous message read with JitterRead: the
+// difference between the time elapsed locally between the two reads and the
+// time elapsed between their source times (srctime). Positive values mean the
+// message arrived later than its source timing predicts. Arrival is the time
+// the read returns, so messages that queued in the receive buffer appear late.
+//
+// The first message has no prior sample and reports a jitter of 0, as does a
+// message without source time, which also restarts the measurement. Source
+// time deltas that are negative by more than half the 32 bit packet timestamp
+// range are treated as a timestamp wraparound.
+func (s SrtSocket) JitterRead(b []byte) (n int, jitter time.Duration, err error) {
+	var ctrl MsgCtrl
+	n, err = s.ReadInto(b, &ctrl)
+	if err != nil {
+		return n, 0, err
+	}
+	arrival := time.Now()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	prev := s.state.jitter
+	if ctrl.SrcTime == 0 {
+		s.state.jitter = nil
+		return n, 0, nil
+	}
+	if prev == nil {
+		s.state.jitter = &jitterState{srcTime: ctrl.SrcTime, arrival: arrival}
+		return n, 0, nil
+	}
+	jitter = transitVariation(prev.srcTime, ctrl.SrcTime, arrival.Sub(prev.arrival))
+	prev.srcTime = ctrl.SrcTime
+	prev.arrival = arrival
+	return n, jitter, nil
+}
+
+// transitVariation returns the difference between the local elapsed time and
+// the elapsed source time (in microseconds), handling timestamp wraparound
+func transitVariation(prevSrcTime, srcTime int64, elapsed time.Duration) time.Duration {
+	srcDelta := srcTime - prevSrcTime
+	if srcDelta < -srcTimeWrapUs/2 {
+		srcDelta += srcTimeWrapUs
+	}
+	return elapsed - time.Duration(srcDelta)*time.Microsecond
+}
@@ -0,0 +1,46 @@
+package srtgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransitVariation(t *testing.T) {
+	cases := []struct {
+		prev, cur int64
+		elapsed   time.Duration
+		expected  time.Duration
+	}{
+		{1000, 21000, 20 * time.Millisecond, 0},
+		{1000, 21000, 25 * time.Millisecond, 5 * time.Millisecond},
+		{1000, 21000, 15 * time.Millisecond, -5 * time.Millisecond},
+		// 32 bit timestamp wraparound
+		{srcTimeWrapUs - 10000, 10000, 20 * time.Millisecond, 0},
+	}
+	for _, c := range cases {
+		if got := transitVariation(c.prev, c.cur, c.elapsed); got != c.expected {
+			t.Errorf("transitVariation(%d, %d, %s) = %s, expected %s", c.prev, c.cur, c.elapsed, got, c.expected)
+		}
+	}
+}
+
+func TestJitterRead(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	buf := make([]byte, 1500)
+	for i := 0; i < 3; i++ {
+		if _, err := caller.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+		_, jitter, err := remote.JitterRead(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && jitter != 0 {
+			t.Errorf("first packet should report no jitter, got %s", jitter)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
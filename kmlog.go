@@ -0,0 +1,66 @@
+package srtgo
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+
+import (
+	"fmt"
+	"time"
+)
+
+// kmLogArea is the log area of key material transition messages, the one SRT
+// uses for its own encryption messages
+const kmLogArea = "HAICRYPT"
+
+// SetKmStateLogging enables or disables logging of the key material state
+// transitions of the socket, as an audit trail of when encryption was
+// established or failed. While enabled, the state is polled every interval
+// (1s if not positive) as in EncryptionStateEvents, and every change of the
+// sending or receiving direction is passed to the handler installed with
+// SrtSetLogHandler, with area "HAICRYPT" and file "srtgo", e.g.
+// "@123 receive key material: securing -> secured". Failures (nosecret,
+// badsecret) are logged at SrtLogLevelErr, other transitions at
+// SrtLogLevelNotice. Logging stops when disabled, when the socket is closed
+// or when the connection breaks.
+//
+// Periodic key refreshes are not logged: they leave the state at secured, and
+// SRT offers no other way to observe them, see EncryptionStateEvents.
+func (s SrtSocket) SetKmStateLogging(enabled bool, interval time.Duration) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	// Closing the channel stops the running logger
+	if s.state.kmLogStop != nil {
+		close(s.state.kmLogStop)
+		s.state.kmLogStop = nil
+	}
+	if !enabled {
+		return
+	}
+	stop := make(chan struct{})
+	s.state.kmLogStop = stop
+	events := s.encryptionStateEvents(interval, stop)
+	go func() {
+		for ev := range events {
+			logKmTransition(s.socket, ev)
+		}
+	}()
+}
+
+// logKmTransition logs the directions whose state changed in ev
+func logKmTransition(socket C.SRTSOCKET, ev KmStateEvent) {
+	if ev.Send != ev.PrevSend {
+		logKmDirection(socket, "send", ev.PrevSend, ev.Send)
+	}
+	if ev.Receive != ev.PrevReceive {
+		logKmDirection(socket, "receive", ev.PrevReceive, ev.Receive)
+	}
+}
+
+func logKmDirection(socket C.SRTSOCKET, direction string, prev, state SrtKmState) {
+	level := SrtLogLevelNotice
+	if state == SrtKmStateNoSecret || state == SrtKmStateBadSecret {
+		level = SrtLogLevelErr
+	}
+	emitLog(level, kmLogArea, fmt.Sprintf("@%d %s key material: %s -> %s", int(socket), direction, prev, state))
+}
@@ -0,0 +1,73 @@
+package srtgo
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// LatencyConfig describes the TSBPD latency settings of a socket. Zero
+// durations are left unset, so SRT keeps its defaults (120ms in live mode).
+//
+// SRT applies latency to both directions: it sets rcvlatency and peerlatency
+// at once, so combining it with either of them is ambiguous and rejected.
+// During the handshake each direction uses the larger of the receiver's
+// rcvlatency and the sender's peerlatency. Latency only has an effect with
+// TSBPD enabled, so non-zero latencies are rejected together with DisableTSBPD.
+type LatencyConfig struct {
+	DisableTSBPD bool          // turn off timestamp-based packet delivery (tsbpdmode = 0)
+	Latency      time.Duration // latency of both directions (latency)
+	RcvLatency   time.Duration // latency when receiving (rcvlatency)
+	PeerLatency  time.Duration // minimum latency requested from the peer receiver (peerlatency)
+}
+
+// ConfigureLatency validates and applies a coherent set of latency options.
+// These are PRE options, so this must be called before connecting.
+func (s SrtSocket) ConfigureLatency(cfg LatencyConfig) error {
+	opts, err := latencyOptions(cfg)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		if err := s.setOption(opt[0], opt[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latencyOptions returns the options to set, in order, for a latency configuration
+func latencyOptions(cfg LatencyConfig) ([][2]string, error) {
+	for name, d := range map[string]time.Duration{"latency": cfg.Latency, "rcvlatency": cfg.RcvLatency, "peerlatency": cfg.PeerLatency} {
+		if d < 0 {
+			return nil, fmt.Errorf("invalid %s %s (must not be negative)", name, d)
+		}
+		if d%time.Millisecond != 0 {
+			return nil, fmt.Errorf("invalid %s %s (must be a whole number of milliseconds)", name, d)
+		}
+	}
+	if cfg.Latency > 0 && (cfg.RcvLatency > 0 || cfg.PeerLatency > 0) {
+		return nil, fmt.Errorf("latency sets both rcvlatency and peerlatency and cannot be combined with them")
+	}
+	if cfg.DisableTSBPD {
+		if cfg.Latency > 0 || cfg.RcvLatency > 0 || cfg.PeerLatency > 0 {
+			return nil, fmt.Errorf("latency has no effect with TSBPD disabled")
+		}
+		return [][2]string{{"tsbpdmode", "0"}}, nil
+	}
+
+	ms := func(d time.Duration) string {
+		return strconv.FormatInt(int64(d/time.Millisecond), 10)
+	}
+	var opts [][2]string
+	if cfg.Latency > 0 {
+		opts = append(opts, [2]string{"latency", ms(cfg.Latency)})
+	}
+	if cfg.RcvLatency > 0 {
+		opts = append(opts, [2]string{"rcvlatency", ms(cfg.RcvLatency)})
+	}
+	if cfg.PeerLatency > 0 {
+		opts = append(opts, [2]string{"peerlatency", ms(cfg.PeerLatency)})
+	}
+	return opts, nil
+}
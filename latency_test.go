@@ -0,0 +1,61 @@
+package srtgo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatencyOptions(t *testing.T) {
+	opts, err := latencyOptions(LatencyConfig{Latency: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opts, [][2]string{{"latency", "200"}}) {
+		t.Errorf("unexpected options %v", opts)
+	}
+
+	opts, err = latencyOptions(LatencyConfig{RcvLatency: 300 * time.Millisecond, PeerLatency: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]string{{"rcvlatency", "300"}, {"peerlatency", "100"}}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("expected %v, got %v", expected, opts)
+	}
+
+	opts, err = latencyOptions(LatencyConfig{DisableTSBPD: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opts, [][2]string{{"tsbpdmode", "0"}}) {
+		t.Errorf("unexpected options %v", opts)
+	}
+}
+
+func TestLatencyOptionsConflicts(t *testing.T) {
+	cases := map[string]LatencyConfig{
+		"tsbpd off with latency":     {DisableTSBPD: true, Latency: 120 * time.Millisecond},
+		"tsbpd off with rcvlatency":  {DisableTSBPD: true, RcvLatency: 120 * time.Millisecond},
+		"tsbpd off with peerlatency": {DisableTSBPD: true, PeerLatency: 120 * time.Millisecond},
+		"latency with rcvlatency":    {Latency: 120 * time.Millisecond, RcvLatency: 200 * time.Millisecond},
+		"latency with peerlatency":   {Latency: 120 * time.Millisecond, PeerLatency: 200 * time.Millisecond},
+		"negative latency":           {Latency: -time.Millisecond},
+		"sub-millisecond latency":    {RcvLatency: 1500 * time.Microsecond},
+	}
+	for name, cfg := range cases {
+		if _, err := latencyOptions(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestConfigureLatencyAfterConnect(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if err := caller.ConfigureLatency(LatencyConfig{Latency: 200 * time.Millisecond}); err == nil {
+		t.Error("latency cannot be changed on a connected socket")
+	}
+}
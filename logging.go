@@ -2,13 +2,17 @@ package srtgo
 
 /*
 #cgo LDFLAGS: -lsrt
+#include <stdlib.h>
 #include <srt/srt.h>
 extern void srtLogCB(void* opaque, int level, const char* file, int line, const char* area, const char* message);
 */
 import "C"
 
 import (
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	gopointer "github.com/mattn/go-pointer"
@@ -63,13 +67,132 @@ const (
 	SrtLogFAEPollAPI  SrtLogFA = 46
 )
 
+// srtLogFANames maps functional areas to the names SRT uses for them,
+// i.e. the SRT_LOGFA_ constant suffixes
+var srtLogFANames = map[SrtLogFA]string{
+	SrtLogFAGeneral:  "GENERAL",
+	SrtLogFASockMgmt: "SOCKMGMT",
+	SrtLogFAConn:     "CONN",
+	SrtLogFAXTimer:   "XTIMER",
+	SrtLogFATsbpd:    "TSBPD",
+	SrtLogFARsrc:     "RSRC",
+	SrtLogFAHaiCrypt: "HAICRYPT",
+	SrtLogFACongest:  "CONGEST",
+	SrtLogFAPFilter:  "PFILTER",
+	SrtLogFAAppLog:   "APPLOG",
+	SrtLogFAAPICtrl:  "API_CTRL",
+	SrtLogFAQueCtrl:  "QUE_CTRL",
+	SrtLogFAEPollUpd: "EPOLL_UPD",
+	SrtLogFAAPIRecv:  "API_RECV",
+	SrtLogFABufRecv:  "BUF_RECV",
+	SrtLogFAQueRecv:  "QUE_RECV",
+	SrtLogFAChnRecv:  "CHN_RECV",
+	SrtLogFAGrpRecv:  "GRP_RECV",
+	SrtLogFAAPISend:  "API_SEND",
+	SrtLogFABufSend:  "BUF_SEND",
+	SrtLogFAQueSend:  "QUE_SEND",
+	SrtLogFAChnSend:  "CHN_SEND",
+	SrtLogFAGrpSend:  "GRP_SEND",
+	SrtLogFAInternal: "INTERNAL",
+	SrtLogFAQueMgmt:  "QUE_MGMT",
+	SrtLogFAChnMgmt:  "CHN_MGMT",
+	SrtLogFAGrpMgmt:  "GRP_MGMT",
+	SrtLogFAEPollAPI: "EPOLL_API",
+}
+
+// String returns the SRT name of the functional area, e.g. "CONN"
+func (fa SrtLogFA) String() string {
+	if name, ok := srtLogFANames[fa]; ok {
+		return name
+	}
+	return "SrtLogFA(" + strconv.Itoa(int(fa)) + ")"
+}
+
+// ParseLogFA maps an SRT log area name such as "CONN" or "TSBPD" to its
+// SrtLogFA constant. Matching ignores case, underscores and an optional
+// "SRT_LOGFA_" prefix, so "api_ctrl", "APICTRL" and "SRT_LOGFA_API_CTRL"
+// are all accepted.
+func ParseLogFA(area string) (SrtLogFA, bool) {
+	key := normalizeLogFAName(area)
+	if key == "" {
+		return 0, false
+	}
+	for fa, name := range srtLogFANames {
+		if normalizeLogFAName(name) == key {
+			return fa, true
+		}
+	}
+	return 0, false
+}
+
+func normalizeLogFAName(name string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "SRT_LOGFA_")
+	return strings.Replace(name, "_", "", -1)
+}
+
 var (
 	logCBPtr     unsafe.Pointer = nil
 	logCBPtrLock sync.Mutex
 )
 
+// logFilter drops log messages in the Go layer before they reach the user callback
+type logFilter struct {
+	minLevel SrtLogLevel
+	fas      map[SrtLogFA]bool
+}
+
+// currentLogFilter holds the active *logFilter, nil when filtering is disabled
+var currentLogFilter atomic.Value
+
+// SetLogFilter restricts the messages passed to the log handler to those at
+// least as severe as minLevel and, if fas is not empty, emitted by one of the
+// given functional areas. Messages whose area cannot be mapped with ParseLogFA
+// are dropped when fas is not empty. Unlike SrtSetLogLevel and SrtAddLogFA the
+// filter runs in Go after SRT emitted the message, but it short-circuits
+// before any string conversion or user callback invocation.
+func SetLogFilter(minLevel SrtLogLevel, fas []SrtLogFA) {
+	f := &logFilter{minLevel: minLevel}
+	if len(fas) > 0 {
+		f.fas = make(map[SrtLogFA]bool, len(fas))
+		for _, fa := range fas {
+			f.fas[fa] = true
+		}
+	}
+	currentLogFilter.Store(f)
+}
+
+// ClearLogFilter removes the filter installed with SetLogFilter
+func ClearLogFilter() {
+	currentLogFilter.Store((*logFilter)(nil))
+}
+
+// allowsLevel reports whether messages of the given level pass the filter
+func (f *logFilter) allowsLevel(level SrtLogLevel) bool {
+	return f == nil || level <= f.minLevel
+}
+
+// allowsArea reports whether messages of the given area pass the filter
+func (f *logFilter) allowsArea(area string) bool {
+	if f == nil || f.fas == nil {
+		return true
+	}
+	fa, ok := ParseLogFA(area)
+	return ok && f.fas[fa]
+}
+
+func loadLogFilter() *logFilter {
+	f, _ := currentLogFilter.Load().(*logFilter)
+	return f
+}
+
 //export srtLogCBWrapper
 func srtLogCBWrapper(arg unsafe.Pointer, level C.int, file *C.char, line C.int, area, message *C.char) {
+	if f := loadLogFilter(); f != nil {
+		if !f.allowsLevel(SrtLogLevel(level)) || !f.allowsArea(C.GoString(area)) {
+			return
+		}
+	}
 	userCB := gopointer.Restore(arg).(LogCallBackFunc)
 	// Call directly instead of creating a new goroutine to reduce overhead
 	// The user callback should handle any necessary async processing
@@ -100,6 +223,62 @@ func storeLogCBPtr(ptr unsafe.Pointer) {
 	logCBPtr = ptr
 }
 
+// cLogMessage is a log message held in C memory, so that it can be passed to
+// srtLogCB the way SRT passes its own messages, e.g. to measure the callback
+// path in benchmarks
+type cLogMessage struct {
+	level, line         C.int
+	file, area, message *C.char
+}
+
+func newCLogMessage(level SrtLogLevel, file string, line int, area, message string) *cLogMessage {
+	return &cLogMessage{
+		level:   C.int(level),
+		line:    C.int(line),
+		file:    C.CString(file),
+		area:    C.CString(area),
+		message: C.CString(message),
+	}
+}
+
+// deliver passes the message to the log handler installed with
+// SrtSetLogHandler through srtLogCB, the entry point SRT calls
+func (m *cLogMessage) deliver() {
+	logCBPtrLock.Lock()
+	ptr := logCBPtr
+	logCBPtrLock.Unlock()
+	if ptr != nil {
+		C.srtLogCB(ptr, m.level, m.file, m.line, m.area, m.message)
+	}
+}
+
+func (m *cLogMessage) free() {
+	C.free(unsafe.Pointer(m.file))
+	C.free(unsafe.Pointer(m.area))
+	C.free(unsafe.Pointer(m.message))
+}
+
+// srtgoLogFile is the file name reported for log messages emitted by srtgo
+// itself rather than by SRT
+const srtgoLogFile = "srtgo"
+
+// emitLog passes a message generated by srtgo to the log handler installed with
+// SrtSetLogHandler, if any. The log filter applies, the SRT log level does not.
+func emitLog(level SrtLogLevel, area, message string) {
+	if f := loadLogFilter(); !f.allowsLevel(level) || !f.allowsArea(area) {
+		return
+	}
+	logCBPtrLock.Lock()
+	var userCB LogCallBackFunc
+	if logCBPtr != nil {
+		userCB, _ = gopointer.Restore(logCBPtr).(LogCallBackFunc)
+	}
+	logCBPtrLock.Unlock()
+	if userCB != nil {
+		userCB(level, srtgoLogFile, 0, area, message)
+	}
+}
+
 func SrtAddLogFA(fa SrtLogFA) {
 	C.srt_addlogfa(C.int(fa))
 }
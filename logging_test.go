@@ -0,0 +1,135 @@
+package srtgo
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseLogFA(t *testing.T) {
+	for fa, name := range srtLogFANames {
+		parsed, ok := ParseLogFA(name)
+		if !ok || parsed != fa {
+			t.Errorf("ParseLogFA(%q) = %d, %t; expected %d", name, parsed, ok, fa)
+		}
+		if fa.String() != name {
+			t.Errorf("SrtLogFA(%d).String() = %q; expected %q", fa, fa.String(), name)
+		}
+	}
+
+	aliases := map[string]SrtLogFA{
+		"conn":               SrtLogFAConn,
+		"tsbpd":              SrtLogFATsbpd,
+		"apictrl":            SrtLogFAAPICtrl,
+		"SRT_LOGFA_API_RECV": SrtLogFAAPIRecv,
+	}
+	for area, expected := range aliases {
+		if fa, ok := ParseLogFA(area); !ok || fa != expected {
+			t.Errorf("ParseLogFA(%q) = %d, %t; expected %d", area, fa, ok, expected)
+		}
+	}
+
+	if _, ok := ParseLogFA("NOPE"); ok {
+		t.Error("unknown area should not parse")
+	}
+}
+
+func TestLogFilter(t *testing.T) {
+	SetLogFilter(SrtLogLevelWarning, []SrtLogFA{SrtLogFAConn})
+	defer ClearLogFilter()
+
+	f := loadLogFilter()
+	if !f.allowsLevel(SrtLogLevelErr) || !f.allowsLevel(SrtLogLevelWarning) {
+		t.Error("messages at or above the minimum level should pass")
+	}
+	if f.allowsLevel(SrtLogLevelDebug) {
+		t.Error("debug messages should be filtered")
+	}
+	if !f.allowsArea("CONN") || f.allowsArea("TSBPD") || f.allowsArea("bogus") {
+		t.Error("area filtering does not match the configured areas")
+	}
+
+	ClearLogFilter()
+	if f := loadLogFilter(); !f.allowsLevel(SrtLogLevelDebug) || !f.allowsArea("TSBPD") {
+		t.Error("cleared filter should let everything pass")
+	}
+}
+
+func TestLogKmTransition(t *testing.T) {
+	type logLine struct {
+		level   SrtLogLevel
+		file    string
+		area    string
+		message string
+	}
+	var lines []logLine
+	SrtSetLogHandler(func(level SrtLogLevel, file string, line int, area, message string) {
+		lines = append(lines, logLine{level, file, area, message})
+	})
+	defer SrtUnsetLogHandler()
+
+	logKmTransition(42, KmStateEvent{
+		PrevSend:    SrtKmStateSecured,
+		Send:        SrtKmStateSecured,
+		PrevReceive: SrtKmStateSecuring,
+		Receive:     SrtKmStateBadSecret,
+	})
+	if len(lines) != 1 {
+		t.Fatalf("expected one line for the changed direction, got %d", len(lines))
+	}
+	expected := logLine{SrtLogLevelErr, "srtgo", "HAICRYPT", "@42 receive key material: securing -> badsecret"}
+	if lines[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, lines[0])
+	}
+
+	SetLogFilter(SrtLogLevelErr, nil)
+	defer ClearLogFilter()
+	logKmTransition(42, KmStateEvent{PrevSend: SrtKmStateSecuring, Send: SrtKmStateSecured})
+	if len(lines) != 1 {
+		t.Error("notice level transition should be filtered")
+	}
+}
+
+// Simulates a stream of debug messages from mixed areas delivered by SRT
+// through the log callback, and counts how many reach the user callback with
+// and without a filter
+func benchmarkLogFilter(b *testing.B, filter bool) {
+	var invocations int64
+	SrtSetLogHandler(func(level SrtLogLevel, file string, line int, area, message string) {
+		atomic.AddInt64(&invocations, 1)
+	})
+	defer SrtUnsetLogHandler()
+	if filter {
+		SetLogFilter(SrtLogLevelNotice, []SrtLogFA{SrtLogFAConn})
+	} else {
+		ClearLogFilter()
+	}
+	defer ClearLogFilter()
+
+	messages := []*cLogMessage{
+		newCLogMessage(SrtLogLevelDebug, "core.cpp", 100, "CONN", "processing handshake"),
+		newCLogMessage(SrtLogLevelDebug, "tsbpd_time.cpp", 200, "TSBPD", "drift sample"),
+		newCLogMessage(SrtLogLevelDebug, "queue.cpp", 300, "QUE_RECV", "packet received"),
+		newCLogMessage(SrtLogLevelNotice, "buffer_snd.cpp", 400, "BUF_SEND", "sender buffer full"),
+	}
+	defer func() {
+		for _, m := range messages {
+			m.free()
+		}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		messages[i%len(messages)].deliver()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(atomic.LoadInt64(&invocations))/float64(b.N), "callbacks/msg")
+}
+
+func BenchmarkLogNoFilter(b *testing.B) {
+	benchmarkLogFilter(b, false)
+}
+
+func BenchmarkLogFilter(b *testing.B) {
+	benchmarkLogFilter(b, true)
+}
@@ -0,0 +1,243 @@
+package srtgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// In live mode, where SRT carries a message in a single packet, WriteMessage
+// and ReadMessage use a framing protocol of srtgo on top of SRT messages, not
+// part of SRT: each fragment starts with a one byte header holding boundary
+// flags, with the same values as the packet boundary (PB) field of SRT data
+// packets. Peers that are not srtgo applications calling ReadMessage see these
+// headers as part of the data. In file message mode SRT delivers messages of
+// many packets whole, so no framing is added there.
+const (
+	boundarySubsequent = byte(0)
+	boundaryLast       = byte(1)
+	boundaryFirst      = byte(2)
+	boundarySolo       = boundaryFirst | boundaryLast
+)
+
+// messageHeaderSize is the per-fragment header added by WriteMessage
+const messageHeaderSize = 1
+
+// MaxMessageSize returns the largest message WriteMessage accepts: SRT can
+// only keep a flow control window (the fc option, in packets) of data in
+// flight, so in live mode a message may span at most fc fragments of payload
+// size each. In file message mode it is the largest message SRT carries, see
+// ReadWholeMessage.
+func (s SrtSocket) MaxMessageSize() (int, error) {
+	l, err := s.messageMode()
+	if err != nil {
+		return 0, err
+	}
+	if !l.live {
+		return l.max, nil
+	}
+	fc, err := s.GetSockOptInt(SRTO_FC)
+	if err != nil {
+		return 0, err
+	}
+	return fc * (l.payload - messageHeaderSize), nil
+}
+
+// WriteMessage sends b as one logical message for a peer reading with
+// ReadMessage. In file message mode (messageapi) this is a single SRT message,
+// which SRT splits into packets and delivers whole to any SRT receiver; SRT
+// cannot send an empty message there. In live mode, where a single Write
+// cannot exceed the payload size, b is split into as many packets as needed
+// with the srtgo-only framing described above: each packet carries a one byte
+// header with the boundary flags (first, subsequent, last or solo) so that
+// ReadMessage can reassemble the message. Messages larger than MaxMessageSize
+// are rejected with ELargeMsg. WriteMessage fails in stream mode, see
+// MessageAPI.
+func (s SrtSocket) WriteMessage(b []byte) (int, error) {
+	l, err := s.messageMode()
+	if err != nil {
+		return 0, err
+	}
+	maxSize, err := s.MaxMessageSize()
+	if err != nil {
+		return 0, err
+	}
+	if len(b) > maxSize {
+		return 0, fmt.Errorf("message of %d bytes exceeds maximum of %d: %w", len(b), maxSize, ELargeMsg)
+	}
+	if !l.live {
+		if len(b) == 0 {
+			return 0, errors.New("SRT cannot send an empty message in file mode")
+		}
+		return s.Write(b)
+	}
+
+	chunkSize := l.payload - messageHeaderSize
+	packet := make([]byte, chunkSize+messageHeaderSize)
+	sent := 0
+	for first := true; first || sent < len(b); first = false {
+		end := sent + chunkSize
+		if end > len(b) {
+			end = len(b)
+		}
+
+		flags := boundarySubsequent
+		if first {
+			flags |= boundaryFirst
+		}
+		if end == len(b) {
+			flags |= boundaryLast
+		}
+
+		packet[0] = flags
+		n := copy(packet[messageHeaderSize:], b[sent:end])
+		if _, err := s.Write(packet[:messageHeaderSize+n]); err != nil {
+			return sent, err
+		}
+		sent = end
+	}
+	return sent, nil
+}
+
+// ReadMessage receives one logical message and returns it. In file message
+// mode this is the next SRT message, as read by ReadWholeMessage, whoever sent
+// it. In live mode it reassembles a message sent with WriteMessage by an srtgo
+// peer; data sent otherwise is not a valid message. If fragments were lost
+// (e.g. dropped as too late), the incomplete message is discarded and reading
+// continues with the next message. Like WriteMessage it fails in stream mode.
+func (s SrtSocket) ReadMessage() ([]byte, error) {
+	l, err := s.messageMode()
+	if err != nil {
+		return nil, err
+	}
+	if !l.live {
+		return s.ReadWholeMessage()
+	}
+	buf := make([]byte, l.read)
+	var msg []byte
+	assembling := false
+	for {
+		n, err := s.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n < messageHeaderSize {
+			continue
+		}
+
+		flags := buf[0]
+		payload := buf[messageHeaderSize:n]
+		if flags&boundaryFirst != 0 {
+			msg = msg[:0]
+			assembling = true
+		} else if !assembling {
+			// Missed the start of this message, skip until the next one
+			continue
+		}
+
+		msg = append(msg, payload...)
+		if flags&boundaryLast != 0 {
+			return msg, nil
+		}
+	}
+}
+
+// messageMode returns the message limits for WriteMessage and ReadMessage,
+// rejecting stream mode, where SRT does not keep messages apart
+func (s SrtSocket) messageMode() (messageLimits, error) {
+	l, err := s.messageLimits()
+	if err != nil {
+		return l, err
+	}
+	if l.max == 0 {
+		return l, errors.New("WriteMessage and ReadMessage require message mode")
+	}
+	return l, nil
+}
+
+// MessageWriter receives the messages delivered by ReadMessagesTo
+type MessageWriter interface {
+	// WriteMessage is called once per SRT message. b and ctrl are reused for
+	// the next message and must not be retained after the call returns.
+	WriteMessage(b []byte, ctrl *MsgCtrl) error
+}
+
+// ReadMessagesTo reads messages with ReadInto and passes each one, with its
+// control information, to w until stop is closed or the connection is broken,
+// in which case it returns nil. An error returned by w or any other read error
+// ends the loop and is returned. Closing stop interrupts a pending read
+// without touching the read deadline, which still applies. Only available in
+// non-blocking mode.
+func (s SrtSocket) ReadMessagesTo(w MessageWriter, stop <-chan struct{}) error {
+	if s.blocking {
+		return errors.New("ReadMessagesTo is only available in non-blocking mode")
+	}
+
+	// Closing stop cancels the pending read, the deadline of the socket is
+	// left to other readers
+	ctx, cancel := context.WithCancel(context.Background())
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	defer func() {
+		cancel()
+		<-exited
+	}()
+
+	buf := make([]byte, s.readBufferSize())
+	ctrl := NewMsgCtrl()
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		n, err := s.readInto(ctx, buf, ctrl)
+		if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+			if IsConnectionBroken(err) {
+				return nil
+			}
+			return err
+		}
+		if err := w.WriteMessage(buf[:n], ctrl); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadWholeMessage reads one message in message mode and returns it in a newly
+// allocated slice of exactly its size. A Read into a buffer smaller than the
+// incoming message fails and the message is lost; ReadWholeMessage instead
+// reads into a scratch buffer sized for the largest message the connection
+// can deliver, bounded by the flow control window, so that messages are never
+// truncated. Not available in stream mode.
+func (s SrtSocket) ReadWholeMessage() ([]byte, error) {
+	l, err := s.messageLimits()
+	if err != nil {
+		return nil, err
+	}
+	if l.max == 0 {
+		return nil, errors.New("ReadWholeMessage requires message mode")
+	}
+
+	bp := getMessageBuffer(l.max)
+	defer messageBufferPool.Put(bp)
+
+	n, err := s.Read(*bp)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), (*bp)[:n]...), nil
+}
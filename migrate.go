@@ -0,0 +1,61 @@
+package srtgo
+
+import (
+	"fmt"
+)
+
+// MigrationState holds the parameters needed to establish a replacement for an
+// existing connection, as captured by Migrate
+type MigrationState struct {
+	Host    string
+	Port    uint16
+	Options map[string]string
+}
+
+// Migrate captures the parameters of the socket so that a replacement can be
+// connected with identical settings, e.g. during a rolling deploy. SRT cannot
+// hand over a live connection, so the replacement performs a new handshake.
+//
+// Preserved: the address, all options the socket was created with (including
+// the passphrase, mode and blocking), the current value of every POST option,
+// which may have been changed since (e.g. maxbw), and the streamid.
+//
+// Not preserved: data in the send and receive buffers, sequence numbers and
+// TSBPD timing, session keys (the replacement negotiates new ones from the
+// same passphrase), statistics, deadlines, callbacks and user data.
+//
+// Only meaningful for sockets that initiated the connection: a socket returned
+// by Accept cannot reconnect to its peer.
+func (s SrtSocket) Migrate() (*MigrationState, error) {
+	if s.socket == SRT_INVALID_SOCK {
+		return nil, fmt.Errorf("invalid socket")
+	}
+	options := make(map[string]string, len(s.options))
+	for k, v := range s.options {
+		options[k] = v
+	}
+	for i := range SocketOptions {
+		optDef := &SocketOptions[i]
+		if optDef.Lifecycle() != LifecyclePost || writeOnlySocketOptions[optDef.name] {
+			continue
+		}
+		val, err := s.exportSocketOption(optDef)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", optDef.name, err)
+		}
+		options[optDef.name] = val
+	}
+	streamid, err := s.GetSockOptString(SRTO_STREAMID)
+	if err != nil {
+		return nil, fmt.Errorf("streamid: %w", err)
+	}
+	if streamid != "" {
+		options["streamid"] = streamid
+	}
+	return &MigrationState{Host: s.host, Port: s.port, Options: options}, nil
+}
+
+// NewSocket creates the replacement socket, which still has to be connected
+func (m *MigrationState) NewSocket() *SrtSocket {
+	return NewSrtSocket(m.Host, m.Port, m.Options)
+}
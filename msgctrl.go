@@ -0,0 +1,61 @@
+package srtgo
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+
+// MsgCtrl carries the per-message control information of srt_sendmsg2 and
+// srt_recvmsg2. A MsgCtrl can be reused across calls: it embeds the C
+// structure handed to libsrt, so no allocation takes place per message.
+type MsgCtrl struct {
+	// MsgTTL is the time in ms after which an unsent message is dropped, -1 means infinite
+	MsgTTL int
+	// InOrder requests in-order delivery of the message (message mode only)
+	InOrder bool
+	// Boundary holds the packet boundary flags (file/message mode)
+	Boundary int
+	// SrcTime is the source time of the message in microseconds, 0 means current time
+	SrcTime int64
+	// PktSeq is the sequence number of the first packet of the message (receive only)
+	PktSeq int32
+	// MsgNo is the message number (receive only)
+	MsgNo int32
+
+	c C.SRT_MSGCTRL
+}
+
+// NewMsgCtrl returns a MsgCtrl initialized with the libsrt defaults
+func NewMsgCtrl() *MsgCtrl {
+	m := new(MsgCtrl)
+	m.Reset()
+	return m
+}
+
+// Reset restores the libsrt defaults
+func (m *MsgCtrl) Reset() {
+	C.srt_msgctrl_init(&m.c)
+	m.fromC()
+}
+
+// toC copies the Go fields into the embedded C structure and returns it
+func (m *MsgCtrl) toC() *C.SRT_MSGCTRL {
+	m.c.msgttl = C.int(m.MsgTTL)
+	if m.InOrder {
+		m.c.inorder = 1
+	} else {
+		m.c.inorder = 0
+	}
+	m.c.boundary = C.int(m.Boundary)
+	m.c.srctime = C.int64_t(m.SrcTime)
+	return &m.c
+}
+
+// fromC copies the embedded C structure back into the Go fields
+func (m *MsgCtrl) fromC() {
+	m.MsgTTL = int(m.c.msgttl)
+	m.InOrder = m.c.inorder != 0
+	m.Boundary = int(m.c.boundary)
+	m.SrcTime = int64(m.c.srctime)
+	m.PktSeq = int32(m.c.pktseq)
+	m.MsgNo = int32(m.c.msgno)
+}
@@ -4,6 +4,7 @@ package srtgo
 import "C"
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"net"
@@ -68,13 +69,22 @@ func sockAddrFromIp6(ip net.IP, port uint16) (*C.struct_sockaddr, int, error) {
 }
 
 func CreateAddrInet(name string, port uint16) (*C.struct_sockaddr, int, error) {
+	return createAddrInetContext(context.Background(), name, port)
+}
+
+// lookupIPAddr resolves host names for createAddrInetContext, replaced in tests
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// createAddrInetContext is CreateAddrInet with a context bounding the name
+// resolution; the resolution error, e.g. context.DeadlineExceeded, is wrapped
+func createAddrInetContext(ctx context.Context, name string, port uint16) (*C.struct_sockaddr, int, error) {
 	ip := net.ParseIP(name)
 	if ip == nil {
-		ips, err := net.LookupIP(name)
+		addrs, err := lookupIPAddr(ctx, name)
 		if err != nil {
-			return nil, 0, fmt.Errorf("Error in CreateAddrInet, LookupIP")
+			return nil, 0, fmt.Errorf("Error in CreateAddrInet, LookupIP: %w", err)
 		}
-		ip = ips[0]
+		ip = addrs[0].IP
 	}
 
 	if ip.To4() != nil {
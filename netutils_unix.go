@@ -3,6 +3,8 @@
 package srtgo
 
 import (
+	"net"
+	"runtime"
 	"syscall"
 
 	"golang.org/x/sys/unix"
@@ -15,3 +17,42 @@ const (
 	afINET4             = unix.AF_INET
 	afINET6             = unix.AF_INET6
 )
+
+// probeUDPBufferSize requests a buffer size on a scratch UDP socket and returns
+// the size the kernel granted, which is what SRT's own UDP socket gets
+func probeUDPBufferSize(size int, send bool) (int, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	opt := unix.SO_RCVBUF
+	if send {
+		opt = unix.SO_SNDBUF
+		err = conn.SetWriteBuffer(size)
+	} else {
+		err = conn.SetReadBuffer(size)
+	}
+	if err != nil {
+		return 0, err
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var granted int
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		granted, sockErr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, opt)
+	}); err != nil {
+		return 0, err
+	}
+	if sockErr != nil {
+		return 0, sockErr
+	}
+	// Linux doubles the requested size to account for bookkeeping overhead
+	if runtime.GOOS == "linux" {
+		granted /= 2
+	}
+	return granted, nil
+}
@@ -3,6 +3,7 @@
 package srtgo
 
 import (
+	"net"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
@@ -27,3 +28,35 @@ func init() {
 	sizeofSockAddrInet6 = uint64(unsafe.Sizeof(inet6))
 	sizeofSockaddrAny = uint64(unsafe.Sizeof(any))
 }
+
+// probeUDPBufferSize requests a buffer size on a scratch UDP socket and returns
+// the size the system granted, which is what SRT's own UDP socket gets
+func probeUDPBufferSize(size int, send bool) (int, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	opt := windows.SO_RCVBUF
+	if send {
+		opt = windows.SO_SNDBUF
+		err = conn.SetWriteBuffer(size)
+	} else {
+		err = conn.SetReadBuffer(size)
+	}
+	if err != nil {
+		return 0, err
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var granted int
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		granted, sockErr = windows.GetsockoptInt(windows.Handle(fd), windows.SOL_SOCKET, opt)
+	}); err != nil {
+		return 0, err
+	}
+	return granted, sockErr
+}
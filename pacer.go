@@ -0,0 +1,155 @@
+package srtgo
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PacePolicy selects what a PacedWriter does with a packet that exceeds the rate
+type PacePolicy int
+
+const (
+	// PaceBlock - wait until the rate allows the packet to be sent
+	PaceBlock PacePolicy = iota
+	// PaceDrop - discard the packet and count it as dropped
+	PaceDrop
+)
+
+// defaultPaceBurst is the burst allowance used when none is configured
+const defaultPaceBurst = 10 * time.Millisecond
+
+// PacedWriterConfig sets the rate of a PacedWriter. Exactly one of
+// PacketsPerSecond and BitsPerSecond must be set.
+type PacedWriterConfig struct {
+	PacketsPerSecond int           // rate limit in packets (writes) per second
+	BitsPerSecond    int64         // rate limit in bits per second
+	Burst            time.Duration // unused rate accumulated for bursts, 10ms if zero
+	Policy           PacePolicy    // what to do with packets above the rate
+}
+
+// PacedWriterStats are the packet counters of a PacedWriter
+type PacedWriterStats struct {
+	Sent      uint64 // packets written to the socket
+	SentBytes uint64 // bytes written to the socket
+	Dropped   uint64 // packets discarded by the PaceDrop policy
+}
+
+// PacedWriter sends packets to an SrtSocket at a configured rate using a token
+// bucket. The bucket refills continuously from the elapsed time, so unlike a
+// time.Ticker loop the rate does not drift when individual writes are late.
+// Each Write is one packet. A packet may take the bucket into debt, which is
+// paid back before the next packet is sent, so packets larger than the burst
+// allowance are still sent at the configured average bitrate.
+// A PacedWriter is safe for concurrent use.
+type PacedWriter struct {
+	// Accessed atomically, kept first for 64-bit alignment on 32-bit platforms
+	sent      uint64
+	sentBytes uint64
+	dropped   uint64
+
+	w      io.Writer
+	policy PacePolicy
+	rate   float64 // tokens per second: packets or bytes
+	bytes  bool    // tokens are bytes rather than packets
+	burst  float64 // bucket capacity in tokens
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+// NewPacedWriter creates a PacedWriter sending to s
+func NewPacedWriter(s *SrtSocket, cfg PacedWriterConfig) (*PacedWriter, error) {
+	return newPacedWriter(s, cfg, time.Now, time.Sleep)
+}
+
+func newPacedWriter(w io.Writer, cfg PacedWriterConfig, now func() time.Time, sleep func(time.Duration)) (*PacedWriter, error) {
+	if (cfg.PacketsPerSecond > 0) == (cfg.BitsPerSecond > 0) {
+		return nil, fmt.Errorf("exactly one of PacketsPerSecond and BitsPerSecond must be positive")
+	}
+	if cfg.Policy != PaceBlock && cfg.Policy != PaceDrop {
+		return nil, fmt.Errorf("unknown pace policy %d", cfg.Policy)
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = defaultPaceBurst
+	}
+
+	p := &PacedWriter{
+		w:      w,
+		policy: cfg.Policy,
+		now:    now,
+		sleep:  sleep,
+	}
+	if cfg.PacketsPerSecond > 0 {
+		p.rate = float64(cfg.PacketsPerSecond)
+	} else {
+		p.rate = float64(cfg.BitsPerSecond) / 8
+		p.bytes = true
+	}
+	p.burst = p.rate * burst.Seconds()
+	p.tokens = p.burst
+	p.last = now()
+	return p, nil
+}
+
+// refill adds the tokens accumulated since the last call, up to the burst
+// allowance. Must be called with mu held.
+func (p *PacedWriter) refill() {
+	now := p.now()
+	p.tokens += now.Sub(p.last).Seconds() * p.rate
+	if p.tokens > p.burst {
+		p.tokens = p.burst
+	}
+	p.last = now
+}
+
+// Write sends b as one packet once the rate allows it. With PaceBlock it waits
+// as needed; with PaceDrop a packet above the rate is discarded and reported
+// as written (len(b), nil), like a packet lost on the network, and counted in
+// Stats.
+func (p *PacedWriter) Write(b []byte) (int, error) {
+	cost := 1.0
+	if p.bytes {
+		cost = float64(len(b))
+	}
+
+	p.mu.Lock()
+	p.refill()
+	for p.tokens <= 0 {
+		if p.policy == PaceDrop {
+			p.mu.Unlock()
+			atomic.AddUint64(&p.dropped, 1)
+			return len(b), nil
+		}
+		wait := time.Duration(-p.tokens / p.rate * float64(time.Second))
+		if wait <= 0 {
+			wait = time.Microsecond
+		}
+		p.sleep(wait)
+		p.refill()
+	}
+	p.tokens -= cost
+	p.mu.Unlock()
+
+	n, err := p.w.Write(b)
+	if err == nil {
+		atomic.AddUint64(&p.sent, 1)
+		atomic.AddUint64(&p.sentBytes, uint64(n))
+	}
+	return n, err
+}
+
+// Stats returns the number of packets sent and dropped so far
+func (p *PacedWriter) Stats() PacedWriterStats {
+	return PacedWriterStats{
+		Sent:      atomic.LoadUint64(&p.sent),
+		SentBytes: atomic.LoadUint64(&p.sentBytes),
+		Dropped:   atomic.LoadUint64(&p.dropped),
+	}
+}
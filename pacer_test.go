@@ -0,0 +1,80 @@
+package srtgo
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when the paced writer sleeps
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time        { return c.t }
+func (c *fakeClock) sleep(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestPacedWriterBlock(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	start := clock.t
+	p, err := newPacedWriter(ioutil.Discard, PacedWriterConfig{PacketsPerSecond: 100}, clock.now, clock.sleep)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet := make([]byte, 188)
+	for i := 0; i < 200; i++ {
+		if _, err := p.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 200 packets at 100 pps take 2s, minus the initial burst of one packet
+	elapsed := clock.t.Sub(start)
+	if elapsed < 1970*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("expected about 2s to send 200 packets, took %s", elapsed)
+	}
+	if stats := p.Stats(); stats.Sent != 200 || stats.Dropped != 0 || stats.SentBytes != 200*188 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestPacedWriterDrop(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	cfg := PacedWriterConfig{BitsPerSecond: 8 * 1000 * 1000, Burst: 10 * time.Millisecond, Policy: PaceDrop}
+	p, err := newPacedWriter(ioutil.Discard, cfg, clock.now, clock.sleep)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The burst allows 10000 bytes; the packet taking the bucket into debt is
+	// still sent, the following ones are dropped until time passes
+	packet := make([]byte, 1000)
+	for i := 0; i < 20; i++ {
+		n, err := p.Write(packet)
+		if err != nil || n != len(packet) {
+			t.Fatalf("write: n=%d err=%v", n, err)
+		}
+	}
+	if stats := p.Stats(); stats.Sent != 10 || stats.Dropped != 10 {
+		t.Errorf("expected 10 sent / 10 dropped, got %+v", stats)
+	}
+
+	clock.sleep(time.Millisecond)
+	p.Write(packet)
+	if stats := p.Stats(); stats.Sent != 11 {
+		t.Errorf("expected a packet to be sent after refill, got %+v", stats)
+	}
+}
+
+func TestPacedWriterConfig(t *testing.T) {
+	invalid := []PacedWriterConfig{
+		{},
+		{PacketsPerSecond: 10, BitsPerSecond: 1000},
+		{PacketsPerSecond: 10, Policy: PacePolicy(5)},
+	}
+	for _, cfg := range invalid {
+		if _, err := NewPacedWriter(nil, cfg); err == nil {
+			t.Errorf("%+v should be rejected", cfg)
+		}
+	}
+}
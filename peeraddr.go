@@ -0,0 +1,105 @@
+package srtgo
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// PeerAddr returns the address of the connected peer, as seen by srt_getpeername
+func (s SrtSocket) PeerAddr() (*net.UDPAddr, error) {
+	var addr syscall.RawSockaddrAny
+	addrlen := C.int(syscall.SizeofSockaddrAny)
+	if C.srt_getpeername(s.socket, (*C.struct_sockaddr)(unsafe.Pointer(&addr)), &addrlen) == SRT_ERROR {
+		return nil, fmt.Errorf("Error getting peer address, %w", srtGetAndClearErrorThreadSafe())
+	}
+	return udpAddrFromSockaddr(&addr)
+}
+
+// localAddr returns the address the socket is bound to, as seen by srt_getsockname
+func (s SrtSocket) localAddr() (*net.UDPAddr, error) {
+	var addr syscall.RawSockaddrAny
+	addrlen := C.int(syscall.SizeofSockaddrAny)
+	if C.srt_getsockname(s.socket, (*C.struct_sockaddr)(unsafe.Pointer(&addr)), &addrlen) == SRT_ERROR {
+		return nil, fmt.Errorf("Error getting socket address, %w", srtGetAndClearErrorThreadSafe())
+	}
+	return udpAddrFromSockaddr(&addr)
+}
+
+// BoundPort returns the local port the socket is bound to. This is the port
+// chosen by the OS when the socket was created with port 0, and is available
+// after Listen, or after Connect for callers, which bind implicitly.
+func (s SrtSocket) BoundPort() (uint16, error) {
+	addr, err := s.localAddr()
+	if err != nil {
+		return 0, err
+	}
+	return uint16(addr.Port), nil
+}
+
+// PeerAddressChangeFunc is called with the previous and the new peer address
+type PeerAddressChangeFunc func(old, new net.Addr)
+
+type peerAddrWatch struct {
+	fn   PeerAddressChangeFunc
+	last *net.UDPAddr
+}
+
+// SetPeerAddressChangeCallback registers fn to be called when the address of
+// the peer differs from the one previously recorded, e.g. for logging or to
+// update firewall rules for roaming clients. SRT raises no event for this, so
+// the address is compared with srt_getpeername after every successful read;
+// fn runs on the reading goroutine and should return quickly. SRT versions
+// that pin the peer address at the handshake and drop packets from other
+// addresses never report a change. A nil fn removes the callback. The callback
+// is released on Close.
+func (s SrtSocket) SetPeerAddressChangeCallback(fn PeerAddressChangeFunc) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if fn == nil {
+		s.state.peerAddr = nil
+		atomic.StoreInt32(&s.state.peerAddrWatched, 0)
+		return nil
+	}
+
+	watch := &peerAddrWatch{fn: fn}
+	// Record the current address if connected, otherwise on the first read
+	if addr, err := s.PeerAddr(); err == nil {
+		watch.last = addr
+	}
+	s.state.peerAddr = watch
+	atomic.StoreInt32(&s.state.peerAddrWatched, 1)
+	return nil
+}
+
+// checkPeerAddress calls the peer address change callback of the socket, if
+// any, when the peer address differs from the recorded one
+func (s SrtSocket) checkPeerAddress() {
+	if s.state == nil || atomic.LoadInt32(&s.state.peerAddrWatched) == 0 {
+		return
+	}
+	s.state.mu.Lock()
+	watch := s.state.peerAddr
+	if watch == nil {
+		s.state.mu.Unlock()
+		return
+	}
+	addr, err := s.PeerAddr()
+	if err != nil {
+		s.state.mu.Unlock()
+		return
+	}
+	old := watch.last
+	watch.last = addr
+	s.state.mu.Unlock()
+
+	if old != nil && (!old.IP.Equal(addr.IP) || old.Port != addr.Port) {
+		watch.fn(old, addr)
+	}
+}
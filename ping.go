@@ -0,0 +1,158 @@
+package srtgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Application level ping messages, sent as one message each:
+//
+//	offset  size  field
+//	0       4     magic "SRTp"
+//	4       1     type: 1 ping, 2 pong
+//	5       8     nonce chosen by the pinger, big endian
+//	13      8     send time of the ping in Unix nanoseconds, big endian
+//
+// The responder answers a ping with a pong that echoes its nonce and send
+// time. Messages of another size or without the magic are not pings, so pings
+// can share a connection with application messages, as long as those never
+// consist of exactly 21 bytes starting with the magic.
+const (
+	pingMessageSize = 21
+	pingTypePing    = 1
+	pingTypePong    = 2
+)
+
+var pingMagic = []byte("SRTp")
+
+// errUnexpectedMessage is returned by Ping for an application message that
+// arrives while waiting for the pong
+var errUnexpectedMessage = errors.New("unexpected message while waiting for pong")
+
+func encodePing(typ byte, nonce uint64, sent int64) []byte {
+	msg := make([]byte, pingMessageSize)
+	copy(msg, pingMagic)
+	msg[4] = typ
+	binary.BigEndian.PutUint64(msg[5:], nonce)
+	binary.BigEndian.PutUint64(msg[13:], uint64(sent))
+	return msg
+}
+
+// decodePing returns the type, nonce and send time of a ping or pong message,
+// ok is false for any other message
+func decodePing(msg []byte) (typ byte, nonce uint64, sent int64, ok bool) {
+	if len(msg) != pingMessageSize || !bytes.HasPrefix(msg, pingMagic) {
+		return 0, 0, 0, false
+	}
+	typ = msg[4]
+	if typ != pingTypePing && typ != pingTypePong {
+		return 0, 0, 0, false
+	}
+	return typ, binary.BigEndian.Uint64(msg[5:]), int64(binary.BigEndian.Uint64(msg[13:])), true
+}
+
+// Ping sends an application level ping and waits up to timeout for the pong
+// of a PongResponder on the peer, returning the round-trip time. Unlike the
+// RTT SRT measures with its own control packets, this covers the whole
+// Read/Write path of both applications, including the latency (TSBPD delay)
+// in live mode, so it confirms the peer application is alive and reports the
+// latency it experiences.
+//
+// Ping reads from the socket while it waits, so it must not be used
+// concurrently with other reads. Pongs of earlier pings that timed out are
+// skipped and pings of the peer are answered; any other message received
+// while waiting ends Ping with an error, so it is meant for connections on
+// which the peer sends nothing but pongs, e.g. a one-way stream to a receiver
+// that reads through a PongResponder.
+// Only available for non-blocking sockets in message mode.
+func (s SrtSocket) Ping(timeout time.Duration) (time.Duration, error) {
+	if s.blocking || s.writeBlocking {
+		return 0, errors.New("Ping requires a non-blocking socket")
+	}
+	if messageAPI, err := s.MessageAPI(); err != nil || !messageAPI {
+		return 0, fmt.Errorf("Ping requires message mode")
+	}
+
+	// The timeout bounds the reads of this call, the read deadline of the
+	// socket is left as it is and still applies
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	nonce := rand.Uint64()
+	start := time.Now()
+	if _, err := s.Write(encodePing(pingTypePing, nonce, start.UnixNano())); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, s.readBufferSize())
+	for {
+		n, err := s.readContext(ctx, buf, nil)
+		if err == context.DeadlineExceeded {
+			return 0, &SrtEpollTimeout{}
+		}
+		if err != nil {
+			return 0, err
+		}
+		typ, got, sent, ok := decodePing(buf[:n])
+		if !ok {
+			return 0, errUnexpectedMessage
+		}
+		if typ == pingTypePing {
+			// Both sides ping each other
+			if _, err := s.Write(encodePing(pingTypePong, got, sent)); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if got == nonce {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// PongResponder reads from a socket on behalf of the application and answers
+// the pings of a peer calling Ping, see Ping for the wire format. It
+// implements io.Reader: Read returns the application messages and consumes
+// pings without returning them.
+type PongResponder struct {
+	s *SrtSocket
+}
+
+// NewPongResponder creates a responder reading from s
+func NewPongResponder(s *SrtSocket) *PongResponder {
+	return &PongResponder{s: s}
+}
+
+// Read reads the next application message into b, answering any ping
+// received before it with a pong. A failure to send a pong is returned.
+func (r *PongResponder) Read(b []byte) (int, error) {
+	for {
+		n, err := r.s.Read(b)
+		if err != nil {
+			return n, err
+		}
+		handled, err := r.Handle(b[:n])
+		if err != nil {
+			return 0, err
+		}
+		if !handled {
+			return n, nil
+		}
+	}
+}
+
+// Handle answers msg with a pong if it is a ping and reports whether it was,
+// for applications with their own read loop. Messages that are not pings are
+// left to the caller.
+func (r *PongResponder) Handle(msg []byte) (bool, error) {
+	typ, nonce, sent, ok := decodePing(msg)
+	if !ok || typ != pingTypePing {
+		return false, nil
+	}
+	_, err := r.s.Write(encodePing(pingTypePong, nonce, sent))
+	return true, err
+}
@@ -0,0 +1,63 @@
+package srtgo
+
+import (
+	"fmt"
+)
+
+// pipeHost is the loopback address Pipe connects on
+const pipeHost = "127.0.0.1"
+
+// Pipe creates a connected pair of live mode, non-blocking sockets on the
+// loopback interface, similar to net.Pipe. The listening side binds an
+// ephemeral port chosen by the OS, so concurrent pipes never collide, and is
+// closed once the connection is accepted. Data written to one end can be read
+// from the other. Both ends must be closed by the caller. Mainly intended for
+// tests; InitSRT must have been called.
+func Pipe() (*SrtSocket, *SrtSocket, error) {
+	return pipe(map[string]string{"transtype": "live"})
+}
+
+// pipe creates a connected pair with the given options applied to both ends
+func pipe(options map[string]string) (*SrtSocket, *SrtSocket, error) {
+	lopts := map[string]string{"mode": "listener"}
+	copts := map[string]string{"mode": "caller"}
+	for k, v := range options {
+		lopts[k] = v
+		copts[k] = v
+	}
+
+	listener := NewSrtSocket(pipeHost, 0, lopts)
+	if listener == nil {
+		return nil, nil, fmt.Errorf("failed to create listener socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		return nil, nil, fmt.Errorf("listen: %w", err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	caller := NewSrtSocket(pipeHost, port, copts)
+	if caller == nil {
+		return nil, nil, fmt.Errorf("failed to create caller socket")
+	}
+	connErr := make(chan error, 1)
+	go func() {
+		connErr <- caller.Connect()
+	}()
+
+	remote, _, err := listener.Accept()
+	if err != nil {
+		caller.Close()
+		<-connErr
+		return nil, nil, fmt.Errorf("accept: %w", err)
+	}
+	if err := <-connErr; err != nil {
+		remote.Close()
+		caller.Close()
+		return nil, nil, fmt.Errorf("connect: %w", err)
+	}
+	return caller, remote, nil
+}
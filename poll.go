@@ -6,6 +6,8 @@ package srtgo
 */
 import "C"
 import (
+	"context"
+	"fmt"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -32,29 +34,42 @@ const (
 	unblockRd: is used to unblock the poller when the socket becomes ready for io
 	rdState: polling state for read operations
 	rdDeadline: deadline in NS before poll operation times out, -1 means timedout (needs to be cleared), 0 is without timeout
+	rdDeadlineAt: absolute deadline as passed to setDeadline, zero if none is set
 	rdSeq: sequence number protects against spurious signalling of timeouts when timer is reset.
 	rdTimer: timer used to enforce deadline.
+	armed: epoll interest currently added by waiters, only used in level triggered mode
+	levelTriggered: the socket uses level triggered polling, see SetSlowConsumer
+	rdGuard/wrGuard: serialize whole read/write operations, so that concurrent callers
+	in the same direction do not race on the wait state
+	state: Go-side state of the socket, for the callbacks run by the poll server
 */
 type pollDesc struct {
-	lock       sync.Mutex
-	closing    bool
-	fd         C.SRTSOCKET
-	pollErr    bool
-	unblockRd  chan interface{}
-	rdState    int32
-	rdLock     sync.Mutex
-	rdDeadline int64
-	rdSeq      int64
-	rdTimer    *time.Timer
-	rtSeq      int64
-	unblockWr  chan interface{}
-	wrState    int32
-	wrLock     sync.Mutex
-	wdDeadline int64
-	wdSeq      int64
-	wdTimer    *time.Timer
-	wtSeq      int64
-	pollS      *pollServer
+	lock           sync.Mutex
+	closing        bool
+	fd             C.SRTSOCKET
+	pollErr        bool
+	unblockRd      chan interface{}
+	rdState        int32
+	rdLock         sync.Mutex
+	rdDeadline     int64
+	rdDeadlineAt   time.Time
+	rdSeq          int64
+	rdTimer        *time.Timer
+	rtSeq          int64
+	unblockWr      chan interface{}
+	wrState        int32
+	wrLock         sync.Mutex
+	wdDeadline     int64
+	wdDeadlineAt   time.Time
+	wdSeq          int64
+	wdTimer        *time.Timer
+	wtSeq          int64
+	armed          C.uint
+	levelTriggered bool
+	rdGuard        sync.Mutex
+	wrGuard        sync.Mutex
+	pollS          *pollServer
+	state          *socketState
 }
 
 var pdPool = sync.Pool{
@@ -68,22 +83,68 @@ var pdPool = sync.Pool{
 	},
 }
 
-func pollDescInit(s C.SRTSOCKET) *pollDesc {
+func pollDescInit(s C.SRTSOCKET, state *socketState) *pollDesc {
 	pd := pdPool.Get().(*pollDesc)
 	pd.lock.Lock()
 	defer pd.lock.Unlock()
 	pd.fd = s
+	pd.state = state
 	pd.rdState = pollDefault
 	pd.wrState = pollDefault
 	pd.pollS = pollServerCtx()
+	pd.levelTriggered = pd.pollS.trigger == PollLevelTriggered
 	pd.closing = false
 	pd.pollErr = false
 	pd.rdSeq++
 	pd.wdSeq++
+	pd.rdDeadline = 0
+	pd.wdDeadline = 0
+	pd.rdDeadlineAt = time.Time{}
+	pd.wdDeadlineAt = time.Time{}
 	pd.pollS.pollOpen(pd)
 	return pd
 }
 
+// guard serializes operations in one direction of a non-blocking socket and
+// returns the function ending the operation. Blocking sockets rely on the
+// locking of SRT itself.
+func (s SrtSocket) guard(mode PollMode) func() {
+	if s.pd == nil || mode == ModeRead && s.blocking || mode == ModeWrite && s.writeBlocking {
+		return func() {}
+	}
+	guard := &s.pd.rdGuard
+	if mode == ModeWrite {
+		guard = &s.pd.wrGuard
+	}
+	guard.Lock()
+	return guard.Unlock
+}
+
+// SetSlowConsumer switches a non-blocking socket to level triggered polling
+// (see PollLevelTriggered) independently of the poll server configuration,
+// for consumers that do not drain the socket on every wakeup, e.g. a reader
+// that processes packets slowly with ReadBatch while more arrive. Every wait
+// then subscribes to readiness while it waits and SRT reports the socket for
+// as long as data is pending, so a wait after a partial read returns at once
+// instead of stalling until new data raises another edge.
+//
+// Level triggering costs two srt_epoll_update_usock calls per wait, and the
+// poll loop wakes up for the socket on every pass while a waiter is parked on
+// a ready socket, so it uses more CPU than edge triggering; enable it only for
+// sockets that need it. Disabling it returns to the poll server default.
+func (s SrtSocket) SetSlowConsumer(enabled bool) error {
+	if s.pd == nil {
+		return fmt.Errorf("slow consumer mode requires a non-blocking socket")
+	}
+	s.pd.lock.Lock()
+	defer s.pd.lock.Unlock()
+	if s.pd.closing {
+		return &SrtSocketClosed{}
+	}
+	s.pd.pollS.setLevelTriggered(s.pd, enabled || s.pd.pollS.trigger == PollLevelTriggered)
+	return nil
+}
+
 func (pd *pollDesc) release() {
 	pd.lock.Lock()
 	defer pd.lock.Unlock()
@@ -91,14 +152,41 @@ func (pd *pollDesc) release() {
 		panic("returning open or blocked upon pollDesc")
 	}
 	pd.fd = 0
+	pd.state = nil
 	pdPool.Put(pd)
 }
 
 func (pd *pollDesc) wait(mode PollMode) error {
+	return pd.waitContext(context.Background(), mode)
+}
+
+// waitUntil is wait that also gives up at deadline, for calls that take a
+// timeout of their own. The deadline applies to this call only: the socket's
+// read/write deadline, which concurrent callers rely on, is left untouched and
+// still applies. Expiry returns SrtEpollTimeout like an expired socket
+// deadline. A zero deadline waits like wait.
+func (pd *pollDesc) waitUntil(deadline time.Time, mode PollMode) error {
+	if deadline.IsZero() {
+		return pd.wait(mode)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	err := pd.waitContext(ctx, mode)
+	if err == context.DeadlineExceeded {
+		return &SrtEpollTimeout{}
+	}
+	return err
+}
+
+// waitContext is wait that also returns ctx.Err() when ctx is done first
+func (pd *pollDesc) waitContext(ctx context.Context, mode PollMode) error {
 	defer pd.reset(mode)
 	if err := pd.checkPollErr(mode); err != nil {
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// Fast path: check if already ready without locking
 	state := &pd.rdState
@@ -146,6 +234,16 @@ func (pd *pollDesc) wait(mode PollMode) error {
 		// Yield to avoid busy spinning
 		runtime.Gosched()
 	}
+	if pd.levelTriggered {
+		pd.pollS.pollArm(pd, mode, true)
+	}
+	// Disarm even if the socket only switched to level triggering during
+	// the wait, see setLevelTriggered
+	defer func() {
+		pd.lock.Lock()
+		pd.pollS.pollArm(pd, mode, false)
+		pd.lock.Unlock()
+	}()
 	pd.lock.Unlock()
 
 wait:
@@ -172,6 +270,17 @@ wait:
 				timerSeq = pd.wtSeq
 			}
 			pd.lock.Unlock()
+		case <-ctx.Done():
+			// Discard a wakeup that raced with the cancellation, so that it
+			// does not end the next wait early; unblock signals under pd.lock
+			pd.lock.Lock()
+			atomic.StoreInt32(state, pollDefault)
+			select {
+			case <-unblockChan:
+			default:
+			}
+			pd.lock.Unlock()
+			return ctx.Err()
 		}
 	}
 	err := pd.checkPollErr(mode)
@@ -180,12 +289,16 @@ wait:
 
 func (pd *pollDesc) close() {
 	pd.lock.Lock()
-	defer pd.lock.Unlock()
 	if pd.closing {
+		pd.lock.Unlock()
 		return
 	}
 	pd.closing = true
 	pd.pollS.pollClose(pd)
+	pd.lock.Unlock()
+	// Wake up goroutines parked in wait, they return SrtSocketClosed
+	pd.unblock(ModeRead, false, false)
+	pd.unblock(ModeWrite, false, false)
 }
 
 func (pd *pollDesc) checkPollErr(mode PollMode) error {
@@ -206,6 +319,14 @@ func (pd *pollDesc) checkPollErr(mode PollMode) error {
 	return nil
 }
 
+// broken reports whether the poll server has seen an error on the socket,
+// i.e. the connection broke, as opposed to the socket being closed locally
+func (pd *pollDesc) broken() bool {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	return pd.pollErr && !pd.closing
+}
+
 func (pd *pollDesc) setDeadline(t time.Time, mode PollMode) {
 	pd.lock.Lock()
 	defer pd.lock.Unlock()
@@ -223,11 +344,12 @@ func (pd *pollDesc) setDeadline(t time.Time, mode PollMode) {
 			pd.rdTimer.Stop()
 		}
 		pd.rdDeadline = d
+		pd.rdDeadlineAt = t
 		if d > 0 {
 			pd.rdTimer.Reset(time.Duration(d))
 		}
 		if d < 0 {
-			pd.unblock(ModeRead, false, false)
+			pd.unblockLocked(ModeRead, false, false)
 		}
 	}
 	if mode == ModeWrite || mode == ModeRead+ModeWrite {
@@ -237,20 +359,37 @@ func (pd *pollDesc) setDeadline(t time.Time, mode PollMode) {
 			pd.wdTimer.Stop()
 		}
 		pd.wdDeadline = d
+		pd.wdDeadlineAt = t
 		if d > 0 {
 			pd.wdTimer.Reset(time.Duration(d))
 		}
 		if d < 0 {
-			pd.unblock(ModeWrite, false, false)
+			pd.unblockLocked(ModeWrite, false, false)
 		}
 	}
 }
 
+// deadline returns the absolute deadline currently set for the given mode,
+// or the zero time if there is none
+func (pd *pollDesc) deadline(mode PollMode) time.Time {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	if mode == ModeWrite {
+		return pd.wdDeadlineAt
+	}
+	return pd.rdDeadlineAt
+}
+
 func (pd *pollDesc) unblock(mode PollMode, pollerr, ioready bool) {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	pd.unblockLocked(mode, pollerr, ioready)
+}
+
+// unblockLocked is unblock for callers already holding pd.lock
+func (pd *pollDesc) unblockLocked(mode PollMode, pollerr, ioready bool) {
 	if pollerr {
-		pd.lock.Lock()
 		pd.pollErr = pollerr
-		pd.lock.Unlock()
 	}
 	state := &pd.rdState
 	unblockChan := pd.unblockRd
@@ -258,12 +397,10 @@ func (pd *pollDesc) unblock(mode PollMode, pollerr, ioready bool) {
 		state = &pd.wrState
 		unblockChan = pd.unblockWr
 	}
-	pd.lock.Lock()
 	old := atomic.LoadInt32(state)
 	if ioready {
 		atomic.StoreInt32(state, pollReady)
 	}
-	pd.lock.Unlock()
 	if old == pollWait {
 		//make sure we never block here
 		select {
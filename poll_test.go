@@ -1,7 +1,11 @@
 package srtgo
 
 import (
+	"io"
+	"os"
+	"os/exec"
 	"testing"
+	"time"
 )
 
 func connectLoop(port uint16, semChan chan struct{}) {
@@ -68,3 +72,287 @@ func BenchmarkAcceptBlockingParallel(b *testing.B) {
 	})
 }
 */
+
+func isRegistered(s *SrtSocket) bool {
+	p := pollServerCtx()
+	p.pollDescLock.Lock()
+	defer p.pollDescLock.Unlock()
+	_, ok := p.pollDescs[s.socket]
+	return ok
+}
+
+func TestBlockingSocketNotPolled(t *testing.T) {
+	InitSRT()
+
+	blocking := NewSrtSocket("127.0.0.1", randomPort(), map[string]string{"blocking": "1"})
+	if blocking == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer blocking.Close()
+	if blocking.pd != nil || isRegistered(blocking) {
+		t.Error("blocking socket was registered with the poll server")
+	}
+
+	nonBlocking := NewSrtSocket("127.0.0.1", randomPort(), map[string]string{"blocking": "0"})
+	if nonBlocking == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer nonBlocking.Close()
+	if !isRegistered(nonBlocking) {
+		t.Error("non-blocking socket was not registered with the poll server")
+	}
+}
+
+func TestCloseIdempotent(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer remote.Close()
+
+	copied := *caller
+	if err := caller.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if isRegistered(&copied) {
+		t.Error("closed socket is still registered with the poll server")
+	}
+	if err := caller.Close(); err != nil {
+		t.Errorf("second Close returned %v", err)
+	}
+	// A copy of the handle still carries the socket id, but shares the
+	// closed state
+	if err := copied.Close(); err != nil {
+		t.Errorf("Close of a copy returned %v", err)
+	}
+	var _ io.Closer = caller
+
+	// The poll server keeps serving the other sockets
+	if !isRegistered(remote) {
+		t.Fatal("remote socket was removed from the poll server")
+	}
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := remote.Read(make([]byte, 1500)); err == nil || !IsTimeout(err) && !IsConnectionBroken(err) {
+		t.Errorf("expected a timeout or a broken connection, got %v", err)
+	}
+}
+
+func TestDisablePollServer(t *testing.T) {
+	// DisablePollServer cannot be undone, so it is tested in a child process
+	if os.Getenv("SRTGO_TEST_DISABLE_POLL") == "1" {
+		if err := DisablePollServer(); err != nil {
+			t.Fatal(err)
+		}
+		InitSRT()
+		s := NewSrtSocket("127.0.0.1", randomPort(), map[string]string{})
+		if s == nil {
+			t.Fatal("Could not create a srt socket")
+		}
+		defer s.Close()
+		if !s.blocking || s.pd != nil {
+			t.Error("socket was not made blocking")
+		}
+		if err := s.SetBlocking(false, false); err == nil {
+			t.Error("expected SetBlocking to reject non-blocking mode")
+		}
+		if NewSrtSocket("127.0.0.1", randomPort(), map[string]string{"blocking": "0"}) != nil {
+			t.Error("expected a non-blocking socket to be refused")
+		}
+		if GetPollServerMetrics() != (PollServerMetrics{}) || phctx != nil {
+			t.Error("poll server was started")
+		}
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDisablePollServer$")
+	cmd.Env = append(os.Environ(), "SRTGO_TEST_DISABLE_POLL=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+
+	// Once the poll server runs it can no longer be disabled
+	InitSRT()
+	s := NewSrtSocket("127.0.0.1", randomPort(), map[string]string{})
+	if s == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer s.Close()
+	if err := DisablePollServer(); err == nil {
+		t.Error("expected DisablePollServer to fail after the poll server started")
+	}
+}
+
+func TestSetPollServerConfigTrigger(t *testing.T) {
+	cfg := DefaultPollServerConfig
+	cfg.Trigger = PollTrigger(7)
+	if err := SetPollServerConfig(cfg); err == nil {
+		t.Error("unknown poll trigger should be rejected")
+	}
+	if DefaultPollServerConfig.Trigger != PollEdgeTriggered {
+		t.Errorf("expected edge triggered default, got %s", DefaultPollServerConfig.Trigger)
+	}
+}
+
+func TestPoller(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	p, err := NewPoller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if err := p.Add(remote, PollIn|PollErr); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := p.Wait(50 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events before writing, got %v", events)
+	}
+
+	if _, err := caller.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	events, err = p.Wait(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Socket != remote || events[0].Events&PollIn == 0 {
+		t.Fatalf("expected read readiness of remote, got %v", events)
+	}
+	buf := make([]byte, 1500)
+	if n, err := remote.Read(buf); err != nil || string(buf[:n]) != "ping" {
+		t.Errorf("unexpected read %q (%v)", buf[:n], err)
+	}
+
+	if err := p.Remove(remote); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPollerPrunesBrokenSockets(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer remote.Close()
+
+	p, err := NewPoller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if err := p.Add(remote, PollIn|PollErr); err != nil {
+		t.Fatal(err)
+	}
+
+	caller.Close()
+	var events []PollResult
+	for start := time.Now(); ; {
+		events, err = p.Wait(100 * time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) > 0 && events[0].Events&PollErr != 0 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the broken connection was not reported")
+		}
+	}
+	if events[0].Socket != remote {
+		t.Errorf("expected the error event of remote, got %v", events)
+	}
+
+	// Reported once, then unsubscribed
+	p.lock.Lock()
+	n := len(p.sockets)
+	p.lock.Unlock()
+	if n != 0 {
+		t.Errorf("expected the broken socket to be pruned, %d sockets left", n)
+	}
+	if events, err := p.Wait(50 * time.Millisecond); err != nil || len(events) != 0 {
+		t.Errorf("expected no further events, got %v (%v)", events, err)
+	}
+	if err := p.Remove(remote); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSlowConsumer(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if err := remote.SetSlowConsumer(true); err != nil {
+		t.Fatal(err)
+	}
+	if !remote.pd.levelTriggered {
+		t.Fatal("expected level triggered polling")
+	}
+
+	const packets = 200
+	payload := make([]byte, 100)
+	for i := 0; i < packets; i++ {
+		if _, err := caller.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A throttled reader takes only a few packets per wakeup, leaving the
+	// rest pending; every following wait must still return without new data
+	buf := make([]byte, 4*len(payload))
+	received := 0
+	for received < packets {
+		remote.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := remote.ReadBatch(buf, 4)
+		if err != nil {
+			t.Fatalf("read stalled after %d packets: %v", received, err)
+		}
+		received += n
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := remote.SetSlowConsumer(false); err != nil {
+		t.Fatal(err)
+	}
+	if remote.pd.levelTriggered != (remote.pd.pollS.trigger == PollLevelTriggered) {
+		t.Error("disabling slow consumer mode should restore the poll server default")
+	}
+}
+
+// BenchmarkPollTrigger compares the cost of a read that waits for data with
+// edge and level triggered polling, the latter paying for the subscription
+// updates of every wait
+func BenchmarkPollTrigger(b *testing.B) {
+	for _, level := range []bool{false, true} {
+		name := "edge"
+		if level {
+			name = "level"
+		}
+		b.Run(name, func(b *testing.B) {
+			InitSRT()
+			caller, remote, err := pipe(map[string]string{"transtype": "live", "tsbpdmode": "0"})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer caller.Close()
+			defer remote.Close()
+			if err := remote.SetSlowConsumer(level); err != nil {
+				b.Fatal(err)
+			}
+
+			payload := make([]byte, 1316)
+			buf := make([]byte, 1316)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := caller.Write(payload); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := remote.Read(buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package srtgo
+
+/*
+#cgo LDFLAGS: -lsrt
+#include <srt/srt.h>
+
+int srt_epoll_uwait_wrapped(int eid, SRT_EPOLL_EVENT* fdsSet, int fdsSize, int64_t msTimeOut, int *srterror, int *syserror)
+{
+	int ret = srt_epoll_uwait(eid, fdsSet, fdsSize, msTimeOut);
+	if (ret < 0) {
+		*srterror = srt_getlasterror(syserror);
+	}
+	return ret;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// srtEpollUWaitImpl waits on an epoll container, reading the error in the same
+// C call so that another goroutine on the thread cannot overwrite it
+func srtEpollUWaitImpl(eid C.int, events []C.SRT_EPOLL_EVENT, timeoutMs C.int64_t) (int, error) {
+	srterr := C.int(0)
+	syserr := C.int(0)
+	res := C.srt_epoll_uwait_wrapped(eid, &events[0], C.int(len(events)), timeoutMs, &srterr, &syserr)
+	if res < 0 {
+		srterror := SRTErrno(srterr)
+		if syserr < 0 {
+			return 0, srterror.wrapSysErr(syscall.Errno(syserr))
+		}
+		return 0, srterror
+	}
+	return int(res), nil
+}
+
+// PollEvent is a set of readiness events of a socket, as used by Poller
+type PollEvent uint32
+
+// Events a socket can be subscribed to with Poller.Add
+const (
+	PollIn  = PollEvent(C.SRT_EPOLL_IN)  // ready to read, or to accept on a listener
+	PollOut = PollEvent(C.SRT_EPOLL_OUT) // ready to write, or connected
+	PollErr = PollEvent(C.SRT_EPOLL_ERR) // broken or closed
+	// PollEdge reports readiness only when it changes (SRT_EPOLL_ET) instead
+	// of on every Wait while the socket is ready
+	PollEdge = PollEvent(C.SRT_EPOLL_ET)
+)
+
+// PollResult is a readiness event returned by Poller.Wait
+type PollResult struct {
+	Socket *SrtSocket
+	Events PollEvent
+}
+
+// Poller is a dedicated SRT epoll set for applications running their own
+// readiness loop, e.g. a single goroutine serving hundreds of sockets. It is
+// independent of the internal poll server used by non-blocking Read and
+// Write, so adding a socket to a Poller does not change how they behave; a
+// Read on a non-blocking socket reported as PollIn returns without parking.
+//
+// Wait blocks the calling goroutine (and its OS thread) inside libsrt, so
+// each Poller should be waited on by a single goroutine.
+type Poller struct {
+	eid     C.int
+	lock    sync.Mutex
+	closed  bool
+	sockets map[C.SRTSOCKET]*SrtSocket
+	events  []C.SRT_EPOLL_EVENT
+}
+
+// NewPoller creates a Poller with its own SRT epoll descriptor
+func NewPoller() (*Poller, error) {
+	eid := C.srt_epoll_create()
+	if eid < 0 {
+		return nil, fmt.Errorf("srt_epoll_create: %w", srtGetAndClearErrorThreadSafe())
+	}
+	// Waiting on an empty set is allowed and just sleeps until the timeout
+	C.srt_epoll_set(eid, C.SRT_EPOLL_ENABLE_EMPTY)
+	return &Poller{
+		eid:     eid,
+		sockets: make(map[C.SRTSOCKET]*SrtSocket),
+	}, nil
+}
+
+// Add subscribes a socket to the given events
+func (p *Poller) Add(s *SrtSocket, events PollEvent) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return fmt.Errorf("poller closed")
+	}
+	//via unsafe.Pointer because SRT_EPOLL_ET does not fit into a C.int
+	ev := C.uint(events)
+	if C.srt_epoll_add_usock(p.eid, s.socket, (*C.int)(unsafe.Pointer(&ev))) == SRT_ERROR {
+		return fmt.Errorf("srt_epoll_add_usock: %w", srtGetAndClearErrorThreadSafe())
+	}
+	p.sockets[s.socket] = s
+	return nil
+}
+
+// Update replaces the events a socket added with Add is subscribed to
+func (p *Poller) Update(s *SrtSocket, events PollEvent) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, exists := p.sockets[s.socket]; !exists {
+		return fmt.Errorf("socket not added to poller")
+	}
+	ev := C.uint(events)
+	if C.srt_epoll_update_usock(p.eid, s.socket, (*C.int)(unsafe.Pointer(&ev))) == SRT_ERROR {
+		return fmt.Errorf("srt_epoll_update_usock: %w", srtGetAndClearErrorThreadSafe())
+	}
+	return nil
+}
+
+// Remove unsubscribes a socket. SRT removes broken and closed sockets on its
+// own; removing them here as well is harmless.
+func (p *Poller) Remove(s *SrtSocket) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, exists := p.sockets[s.socket]; !exists {
+		return nil
+	}
+	delete(p.sockets, s.socket)
+	if C.srt_epoll_remove_usock(p.eid, s.socket) == SRT_ERROR {
+		return fmt.Errorf("srt_epoll_remove_usock: %w", srtGetAndClearErrorThreadSafe())
+	}
+	return nil
+}
+
+// Wait blocks until at least one subscribed socket is ready or the timeout
+// expires, and returns the ready sockets. A negative timeout waits
+// indefinitely; on timeout Wait returns no events and a nil error. The
+// returned slice is allocated for each call and owned by the caller.
+//
+// A socket reported with PollErr is broken or closed and is unsubscribed
+// after that report, as SRT does for closed sockets; Add it again to keep
+// polling it.
+func (p *Poller) Wait(timeout time.Duration) ([]PollResult, error) {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil, fmt.Errorf("poller closed")
+	}
+	if n := len(p.sockets); n > len(p.events) {
+		p.events = make([]C.SRT_EPOLL_EVENT, n)
+	} else if len(p.events) == 0 {
+		p.events = make([]C.SRT_EPOLL_EVENT, 1)
+	}
+	events := p.events
+	p.lock.Unlock()
+
+	timeoutMs := C.int64_t(-1)
+	if timeout >= 0 {
+		timeoutMs = C.int64_t(timeout / time.Millisecond)
+	}
+	n, err := srtEpollUWaitImpl(p.eid, events, timeoutMs)
+	if err != nil {
+		if errors.Is(err, ETimeout) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("srt_epoll_uwait: %w", err)
+	}
+	if n > len(events) {
+		n = len(events)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	results := make([]PollResult, 0, n)
+	for _, ev := range events[:n] {
+		s, exists := p.sockets[ev.fd]
+		if !exists {
+			// Removed while waiting
+			continue
+		}
+		results = append(results, PollResult{Socket: s, Events: PollEvent(ev.events)})
+		if PollEvent(ev.events)&PollErr != 0 {
+			// The socket is broken or closed, and SRT drops closed sockets
+			// from the set on its own: forget it after this report
+			delete(p.sockets, ev.fd)
+			C.srt_epoll_remove_usock(p.eid, ev.fd)
+		}
+	}
+	return results, nil
+}
+
+// Close releases the epoll descriptor. The subscribed sockets are not closed.
+func (p *Poller) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	p.sockets = nil
+	if C.srt_epoll_release(p.eid) == SRT_ERROR {
+		return fmt.Errorf("srt_epoll_release: %w", srtGetAndClearErrorThreadSafe())
+	}
+	return nil
+}
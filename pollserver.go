@@ -7,39 +7,192 @@ package srtgo
 import "C"
 
 import (
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
 var (
 	phctx *pollServer
 	once  sync.Once
+
+	pollConfigLock sync.Mutex
+	pollConfig     = DefaultPollServerConfig
+	pollDisabled   bool
+)
+
+// PollServerConfig tunes the internal epoll loop shared by all non-blocking sockets
+type PollServerConfig struct {
+	// BatchSize is the maximum number of events fetched per srt_epoll_uwait call.
+	// Larger batches reduce syscall overhead for bulk throughput with many sockets,
+	// at the cost of a longer processing pass (and thus wakeup latency) per batch.
+	BatchSize int
+	// TimeoutMs is the srt_epoll_uwait timeout in milliseconds. The loop wakes
+	// up at least this often when idle; smaller values shorten the reaction to
+	// shutdown at the cost of more idle wakeups.
+	TimeoutMs int
+	// Trigger selects edge (default) or level triggered readiness notification.
+	// Edge triggering stays the default because it needs no epoll calls per
+	// wait; BenchmarkPollTrigger measures what level triggering adds to a read
+	// that waits for data.
+	Trigger PollTrigger
+}
+
+// PollTrigger selects how the poll server is notified of socket readiness
+type PollTrigger int
+
+const (
+	// PollEdgeTriggered subscribes every socket permanently with SRT_EPOLL_ET:
+	// readiness is reported once, when it changes. It costs no extra calls per
+	// wait, but a notification that arrives while nobody waits is only kept
+	// until the next read or write resets the wait state; if that operation
+	// does not drain the socket (e.g. ReadBatch with a full buffer), the
+	// remaining data raises no new edge and a subsequent wait may block until
+	// more data arrives or the deadline expires.
+	PollEdgeTriggered PollTrigger = iota
+	// PollLevelTriggered subscribes a socket to read or write readiness only
+	// while a goroutine waits for it, and SRT reports the socket as long as it
+	// is ready, so a wakeup cannot be missed. Every wait costs two additional
+	// srt_epoll_update_usock calls.
+	PollLevelTriggered
 )
 
+// String returns human-readable poll trigger name
+func (t PollTrigger) String() string {
+	switch t {
+	case PollEdgeTriggered:
+		return "edge"
+	case PollLevelTriggered:
+		return "level"
+	default:
+		return "unknown"
+	}
+}
+
+// DefaultPollServerConfig holds the settings used unless SetPollServerConfig is called
+var DefaultPollServerConfig = PollServerConfig{
+	BatchSize: 512,
+	TimeoutMs: 100,
+}
+
+// Accepted ranges for PollServerConfig
+const (
+	minPollBatchSize = 1
+	maxPollBatchSize = 8192
+	minPollTimeoutMs = 1
+	maxPollTimeoutMs = 10000
+)
+
+// SetPollServerConfig configures the internal epoll loop. It must be called
+// before the first non-blocking socket is created; afterwards it returns an error.
+func SetPollServerConfig(cfg PollServerConfig) error {
+	if cfg.BatchSize < minPollBatchSize || cfg.BatchSize > maxPollBatchSize {
+		return fmt.Errorf("poll batch size %d out of range [%d, %d]", cfg.BatchSize, minPollBatchSize, maxPollBatchSize)
+	}
+	if cfg.TimeoutMs < minPollTimeoutMs || cfg.TimeoutMs > maxPollTimeoutMs {
+		return fmt.Errorf("poll timeout %dms out of range [%d, %d]", cfg.TimeoutMs, minPollTimeoutMs, maxPollTimeoutMs)
+	}
+	if cfg.Trigger != PollEdgeTriggered && cfg.Trigger != PollLevelTriggered {
+		return fmt.Errorf("unknown poll trigger %d", cfg.Trigger)
+	}
+	pollConfigLock.Lock()
+	defer pollConfigLock.Unlock()
+	if phctx != nil {
+		return fmt.Errorf("poll server already started")
+	}
+	pollConfig = cfg
+	return nil
+}
+
+// DisablePollServer makes the package blocking only, for applications that
+// never use non-blocking sockets: the internal epoll loop and its goroutine
+// are never started, sockets created without the "blocking" option are
+// blocking, and requesting non-blocking mode fails (NewSrtSocket returns nil
+// for "blocking" set to "0", SetBlocking returns an error). It is mutually
+// exclusive with non-blocking mode, so it must be called before the first
+// non-blocking socket is created; afterwards it returns an error. It cannot be
+// undone.
+func DisablePollServer() error {
+	pollConfigLock.Lock()
+	defer pollConfigLock.Unlock()
+	if phctx != nil {
+		return fmt.Errorf("poll server already started")
+	}
+	pollDisabled = true
+	return nil
+}
+
+// pollServerDisabled reports whether DisablePollServer has been called
+func pollServerDisabled() bool {
+	pollConfigLock.Lock()
+	defer pollConfigLock.Unlock()
+	return pollDisabled
+}
+
+// PollServerMetrics reports activity of the internal epoll loop
+type PollServerMetrics struct {
+	Wakeups  uint64 // srt_epoll_uwait calls that returned events
+	Events   uint64 // total number of events processed
+	MaxBatch uint64 // largest number of events returned by a single wakeup
+}
+
+// GetPollServerMetrics returns the metrics of the internal epoll loop. The
+// average number of events coalesced per wakeup is Events / Wakeups.
+func GetPollServerMetrics() PollServerMetrics {
+	pollConfigLock.Lock()
+	p := phctx
+	pollConfigLock.Unlock()
+	if p == nil {
+		return PollServerMetrics{}
+	}
+	return PollServerMetrics{
+		Wakeups:  atomic.LoadUint64(&p.wakeups),
+		Events:   atomic.LoadUint64(&p.events),
+		MaxBatch: atomic.LoadUint64(&p.maxBatch),
+	}
+}
+
 func pollServerCtx() *pollServer {
 	once.Do(pollServerCtxInit)
 	return phctx
 }
 
 func pollServerCtxInit() {
+	pollConfigLock.Lock()
+	defer pollConfigLock.Unlock()
 	eid := C.srt_epoll_create()
 	C.srt_epoll_set(eid, C.SRT_EPOLL_ENABLE_EMPTY)
 	phctx = &pollServer{
 		srtEpollDescr: eid,
 		pollDescs:     make(map[C.SRTSOCKET]*pollDesc),
+		batchSize:     pollConfig.BatchSize,
+		timeoutMs:     pollConfig.TimeoutMs,
+		trigger:       pollConfig.Trigger,
 	}
 	go phctx.run()
 }
 
 type pollServer struct {
+	wakeups       uint64
+	events        uint64
+	maxBatch      uint64
 	srtEpollDescr C.int
 	pollDescLock  sync.Mutex
 	pollDescs     map[C.SRTSOCKET]*pollDesc
+	batchSize     int
+	timeoutMs     int
+	trigger       PollTrigger
 }
 
 func (p *pollServer) pollOpen(pd *pollDesc) {
 	//use uint because otherwise with ET it would overflow :/ (srt should accept an uint instead, or fix it's SRT_EPOLL_ET definition)
 	events := C.uint(C.SRT_EPOLL_IN | C.SRT_EPOLL_OUT | C.SRT_EPOLL_ERR | C.SRT_EPOLL_ET)
+	if pd.levelTriggered {
+		// Read/write interest is added by pollArm while a goroutine waits
+		events = C.uint(C.SRT_EPOLL_ERR)
+	}
+	pd.armed = 0
 	//via unsafe.Pointer because we cannot cast *C.uint to *C.int directly
 	//block poller
 	p.pollDescLock.Lock()
@@ -51,7 +204,58 @@ func (p *pollServer) pollOpen(pd *pollDesc) {
 	p.pollDescLock.Unlock()
 }
 
+// pollArm adds (arm) or removes the read or write interest of a socket in
+// level triggered mode. Must be called with pd.lock held.
+func (p *pollServer) pollArm(pd *pollDesc, mode PollMode, arm bool) {
+	if !pd.levelTriggered {
+		return
+	}
+	flag := C.uint(C.SRT_EPOLL_IN)
+	if mode == ModeWrite {
+		flag = C.SRT_EPOLL_OUT
+	}
+	armed := pd.armed &^ flag
+	if arm {
+		armed |= flag
+	}
+	if armed == pd.armed {
+		return
+	}
+	pd.armed = armed
+	events := armed | C.SRT_EPOLL_ERR
+	// Fails only if the socket has been closed or broken, which SRT reports
+	// by removing it from the epoll set
+	C.srt_epoll_update_usock(p.srtEpollDescr, pd.fd, (*C.int)(unsafe.Pointer(&events)))
+}
+
+// setLevelTriggered switches the subscription of a socket between edge and
+// level triggered mode. Directions a goroutine currently waits for are armed
+// right away, the waiter disarms them when it returns. Must be called with
+// pd.lock held.
+func (p *pollServer) setLevelTriggered(pd *pollDesc, level bool) {
+	if pd.levelTriggered == level {
+		return
+	}
+	pd.levelTriggered = level
+	pd.armed = 0
+	events := C.uint(C.SRT_EPOLL_IN | C.SRT_EPOLL_OUT | C.SRT_EPOLL_ERR | C.SRT_EPOLL_ET)
+	if level {
+		if atomic.LoadInt32(&pd.rdState) == pollWait {
+			pd.armed |= C.SRT_EPOLL_IN
+		}
+		if atomic.LoadInt32(&pd.wrState) == pollWait {
+			pd.armed |= C.SRT_EPOLL_OUT
+		}
+		events = pd.armed | C.SRT_EPOLL_ERR
+	}
+	// Fails only if the socket has been closed or broken, see pollArm
+	C.srt_epoll_update_usock(p.srtEpollDescr, pd.fd, (*C.int)(unsafe.Pointer(&events)))
+}
+
 func (p *pollServer) pollClose(pd *pollDesc) {
+	p.pollDescLock.Lock()
+	delete(p.pollDescs, pd.fd)
+	p.pollDescLock.Unlock()
 	sockstate := C.srt_getsockstate(pd.fd)
 	//Broken/closed sockets get removed internally by SRT lib
 	if sockstate == C.SRTS_BROKEN || sockstate == C.SRTS_CLOSING || sockstate == C.SRTS_CLOSED || sockstate == C.SRTS_NONEXIST {
@@ -61,9 +265,6 @@ func (p *pollServer) pollClose(pd *pollDesc) {
 	if ret == -1 {
 		panic("ERROR REMOVING FD FROM EPOLL")
 	}
-	p.pollDescLock.Lock()
-	delete(p.pollDescs, pd.fd)
-	p.pollDescLock.Unlock()
 }
 
 func init() {
@@ -73,11 +274,10 @@ func init() {
 func (p *pollServer) run() {
 	// Use a reasonable timeout instead of infinite to prevent busy waiting
 	// and allow for graceful shutdown
-	timeoutMs := C.int64_t(100) // 100ms timeout
-	// Increased from 128 to 512 to handle high-throughput scenarios (60k+ packets/sec)
-	// Larger batch size reduces epoll syscall overhead
-	fds := [512]C.SRT_EPOLL_EVENT{}
-	fdlen := C.int(512)
+	timeoutMs := C.int64_t(p.timeoutMs)
+	// Larger batch size reduces epoll syscall overhead, see PollServerConfig
+	fds := make([]C.SRT_EPOLL_EVENT, p.batchSize)
+	fdlen := C.int(len(fds))
 
 	for {
 		res := C.srt_epoll_uwait(p.srtEpollDescr, &fds[0], fdlen, timeoutMs)
@@ -97,12 +297,24 @@ func (p *pollServer) run() {
 				max = int(fdlen)
 			}
 
+			p.recordWakeup(uint64(max))
 			// Process events in batches to reduce lock contention
 			p.processEvents(fds[:max])
 		}
 	}
 }
 
+func (p *pollServer) recordWakeup(n uint64) {
+	atomic.AddUint64(&p.wakeups, 1)
+	atomic.AddUint64(&p.events, n)
+	for {
+		old := atomic.LoadUint64(&p.maxBatch)
+		if n <= old || atomic.CompareAndSwapUint64(&p.maxBatch, old, n) {
+			return
+		}
+	}
+}
+
 // processEvents handles a batch of events with optimized locking
 func (p *pollServer) processEvents(events []C.SRT_EPOLL_EVENT) {
 	// Take a snapshot of poll descriptors to minimize lock time
@@ -128,6 +340,7 @@ func (p *pollServer) processEvents(events []C.SRT_EPOLL_EVENT) {
 		if eventFlags&C.SRT_EPOLL_ERR != 0 {
 			pd.unblock(ModeRead, true, false)
 			pd.unblock(ModeWrite, true, false)
+			notifyBroken(pd)
 			continue
 		}
 		if eventFlags&C.SRT_EPOLL_IN != 0 {
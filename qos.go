@@ -0,0 +1,72 @@
+package srtgo
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DSCPClass is a Differentiated Services code point (RFC 2474), the upper six
+// bits of the IP ToS / traffic class byte
+type DSCPClass uint8
+
+// Standard DSCP classes
+const (
+	DSCPCS0  DSCPClass = 0 // best effort
+	DSCPCS1  DSCPClass = 8
+	DSCPCS2  DSCPClass = 16
+	DSCPCS3  DSCPClass = 24
+	DSCPCS4  DSCPClass = 32
+	DSCPCS5  DSCPClass = 40
+	DSCPCS6  DSCPClass = 48
+	DSCPCS7  DSCPClass = 56
+	DSCPAF11 DSCPClass = 10 // assured forwarding, class 1, low drop
+	DSCPAF12 DSCPClass = 12
+	DSCPAF13 DSCPClass = 14
+	DSCPAF21 DSCPClass = 18
+	DSCPAF22 DSCPClass = 20
+	DSCPAF23 DSCPClass = 22
+	DSCPAF31 DSCPClass = 26
+	DSCPAF32 DSCPClass = 28
+	DSCPAF33 DSCPClass = 30
+	DSCPAF41 DSCPClass = 34 // assured forwarding, class 4, low drop; common for video
+	DSCPAF42 DSCPClass = 36
+	DSCPAF43 DSCPClass = 38
+	DSCPEF   DSCPClass = 46 // expedited forwarding
+)
+
+// maxDSCP is the largest six bit code point
+const maxDSCP = 63
+
+// dscpShift positions the code point above the two ECN bits of the ToS byte
+const dscpShift = 2
+
+// TOS returns the IP ToS byte carrying the code point, with the ECN bits cleared
+func (c DSCPClass) TOS() int {
+	return int(c) << dscpShift
+}
+
+// SetDSCP marks the packets of the socket with a DSCP class by setting the
+// iptos option. This is a PREBIND option, so it must be called before Listen
+// or Connect.
+func (s SrtSocket) SetDSCP(class DSCPClass) error {
+	if class > maxDSCP {
+		return fmt.Errorf("invalid DSCP class %d (must be 0 to %d)", class, maxDSCP)
+	}
+	return s.setOption("iptos", strconv.Itoa(class.TOS()))
+}
+
+// IP TTL range accepted by SRT
+const (
+	minTTL = 1
+	maxTTL = 255
+)
+
+// SetTTL sets the IP time to live (or IPv6 hop limit) of the packets of the
+// socket (ipttl). This is a PREBIND option, so it must be called before Listen
+// or Connect.
+func (s SrtSocket) SetTTL(ttl int) error {
+	if ttl < minTTL || ttl > maxTTL {
+		return fmt.Errorf("invalid TTL %d (must be %d to %d)", ttl, minTTL, maxTTL)
+	}
+	return s.setOption("ipttl", strconv.Itoa(ttl))
+}
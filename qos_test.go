@@ -0,0 +1,55 @@
+package srtgo
+
+import "testing"
+
+func TestDSCPTOS(t *testing.T) {
+	cases := []struct {
+		class DSCPClass
+		tos   int
+	}{
+		{DSCPCS0, 0x00},
+		{DSCPCS1, 0x20},
+		{DSCPAF41, 0x88},
+		{DSCPEF, 0xb8},
+		{DSCPCS7, 0xe0},
+	}
+	for _, c := range cases {
+		if tos := c.class.TOS(); tos != c.tos {
+			t.Errorf("DSCP %d: expected ToS 0x%02x, got 0x%02x", c.class, c.tos, tos)
+		}
+	}
+}
+
+func TestSetDSCPAndTTL(t *testing.T) {
+	InitSRT()
+	s := NewSrtSocket("127.0.0.1", 0, map[string]string{"mode": "listener"})
+	if s == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer s.Close()
+
+	if err := s.SetDSCP(DSCPEF); err != nil {
+		t.Fatal(err)
+	}
+	if tos, err := s.GetSockOptInt(SRTO_IPTOS); err != nil || tos != 0xb8 {
+		t.Errorf("expected iptos 0xb8, got 0x%02x (%v)", tos, err)
+	}
+	if err := s.SetDSCP(64); err == nil {
+		t.Error("DSCP 64 should be rejected")
+	}
+	if err := s.SetTTL(32); err != nil {
+		t.Fatal(err)
+	}
+	for _, ttl := range []int{0, 256} {
+		if err := s.SetTTL(ttl); err == nil {
+			t.Errorf("TTL %d should be rejected", ttl)
+		}
+	}
+
+	if err := s.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetDSCP(DSCPAF41); err == nil {
+		t.Error("iptos cannot be changed after bind")
+	}
+}
@@ -16,8 +16,13 @@ int srt_recvmsg2_wrapped(SRTSOCKET u, char* buf, int len, SRT_MSGCTRL *mctrl, in
 */
 import "C"
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -36,24 +41,103 @@ func srtRecvMsg2Impl(u C.SRTSOCKET, buf []byte, msgctrl *C.SRT_MSGCTRL) (n int,
 	return
 }
 
-// Read data from the SRT socket
+// Read data from the SRT socket. The semantics follow the messageapi option,
+// see MessageAPI:
+//
+// In message mode (live mode, or file mode with messageapi=1) every Read
+// returns exactly one message. b must be large enough to hold it: a message
+// that does not fit is not delivered partially and SRT reports an error.
+//
+// In stream mode (file mode with messageapi=0, the default there) the data is
+// a byte stream without boundaries, like TCP: a Read returns whatever is
+// available up to len(b), and a short read only means that more data has not
+// arrived yet.
+//
+// Once the peer has closed the connection and the data it sent before has been
+// read, Read returns io.EOF in stream mode, like a TCP connection. A connection
+// lost mid-stream (peer idle timeout) returns EConnLost instead. In message
+// mode SRT reports both cases as EConnLost, as it does not tell them apart.
 func (s SrtSocket) Read(b []byte) (n int, err error) {
+	return s.read(b, nil)
+}
+
+// ReadInto reads a message into b and fills ctrl with its control information.
+// Both b and ctrl are owned by the caller and can be reused across calls, so
+// the read itself does not allocate. A nil ctrl behaves like Read.
+func (s SrtSocket) ReadInto(b []byte, ctrl *MsgCtrl) (n int, err error) {
+	return s.readInto(context.Background(), b, ctrl)
+}
+
+// readInto is ReadInto that gives up waiting for data when ctx is done
+func (s SrtSocket) readInto(ctx context.Context, b []byte, ctrl *MsgCtrl) (n int, err error) {
+	if ctrl == nil {
+		return s.readContext(ctx, b, nil)
+	}
+	C.srt_msgctrl_init(&ctrl.c)
+	n, err = s.readContext(ctx, b, &ctrl.c)
+	ctrl.fromC()
+	return
+}
+
+// ReadContext reads like Read, but gives up waiting for data when ctx is done
+// and returns ctx.Err(). The read deadline still applies. Cancellation only
+// interrupts the wait of a non-blocking socket; a blocking socket is bounded
+// by SetReceiveTimeout instead, so ReadContext rejects it. A cancelled read
+// leaves the socket usable for subsequent reads.
+func (s SrtSocket) ReadContext(ctx context.Context, b []byte) (n int, err error) {
+	if s.blocking {
+		return 0, fmt.Errorf("ReadContext requires a non-blocking socket")
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.readContext(ctx, b, nil)
+}
+
+func (s SrtSocket) read(b []byte, msgctrl *C.SRT_MSGCTRL) (n int, err error) {
+	return s.readContext(context.Background(), b, msgctrl)
+}
+
+func (s SrtSocket) readContext(ctx context.Context, b []byte, msgctrl *C.SRT_MSGCTRL) (n int, err error) {
+	defer s.guard(ModeRead)()
+	defer func() {
+		if err == nil {
+			s.checkPeerAddress()
+		}
+	}()
+	defer func() {
+		if err == nil && n == 0 {
+			// SRT returns 0 in stream mode once the peer has closed cleanly
+			// and the receive buffer is drained
+			err = io.EOF
+		}
+	}()
+
 	// Fast path: try reading immediately
-	n, err = srtRecvMsg2Impl(s.socket, b, nil)
+	n, err = srtRecvMsg2Impl(s.socket, b, msgctrl)
 
 	// If successful or blocking mode, return immediately
 	if err == nil || s.blocking || !errors.Is(err, error(EAsyncRCV)) {
 		return
 	}
 
-	// Non-blocking mode: wait for data to be available
-	if !s.blocking {
+	// Non-blocking mode: wait for data to be available. A wakeup can be
+	// spurious, e.g. when a concurrent reader consumed the data first, so the
+	// wait is repeated until data arrives or the deadline or ctx end it, like
+	// a blocking read.
+	for errors.Is(err, error(EAsyncRCV)) {
 		s.pd.reset(ModeRead)
-		if waitErr := s.pd.wait(ModeRead); waitErr != nil {
-			return 0, waitErr
+		if waitErr := s.pd.waitContext(ctx, ModeRead); waitErr != nil {
+			if !s.pd.broken() {
+				return 0, waitErr
+			}
+			// The connection broke while waiting: read once more, so that
+			// SRT delivers the data left in the receive buffer and then
+			// reports whether the peer closed cleanly
+			return srtRecvMsg2Impl(s.socket, b, msgctrl)
 		}
 		// Try reading again after waiting
-		n, err = srtRecvMsg2Impl(s.socket, b, nil)
+		n, err = srtRecvMsg2Impl(s.socket, b, msgctrl)
 	}
 
 	return
@@ -63,13 +147,28 @@ func (s SrtSocket) Read(b []byte) (n int, err error) {
 // It tries to read up to maxPackets into the provided buffer slice
 // Returns the number of packets successfully read
 // This is useful for high-throughput scenarios where reducing syscall overhead is critical
+// Like Read, it returns io.EOF once a stream mode peer has closed cleanly and no
+// data is left.
+//
+// ReadBatch reads what is currently available: it waits (up to the read
+// deadline, or the receive timeout of a blocking socket) only for the first
+// packet, then collects the packets that are ready at that moment, up to
+// maxPackets, and returns as soon as none is, without waiting again. A
+// blocking socket checks the readiness SRT reports for epoll before each
+// further read, so a packet that TSBPD still holds back ends the batch instead
+// of blocking it. To wait for the batch to fill instead, use
+// ReadBatchDeadline.
 func (s SrtSocket) ReadBatch(buffer []byte, maxPackets int) (packetsRead int, totalBytes int, err error) {
 	if maxPackets <= 0 || len(buffer) == 0 {
 		return 0, 0, nil
 	}
+	defer s.guard(ModeRead)()
 
 	offset := 0
 	for packetsRead = 0; packetsRead < maxPackets && offset < len(buffer); packetsRead++ {
+		if packetsRead > 0 && s.blocking && !s.readReady() {
+			break
+		}
 		// Try to read a packet
 		n, readErr := srtRecvMsg2Impl(s.socket, buffer[offset:], nil)
 
@@ -77,10 +176,11 @@ func (s SrtSocket) ReadBatch(buffer []byte, maxPackets int) (packetsRead int, to
 			// If this is the first packet and we got ASYNCRCV, wait for data
 			if packetsRead == 0 && !s.blocking && errors.Is(readErr, error(EAsyncRCV)) {
 				s.pd.reset(ModeRead)
-				if waitErr := s.pd.wait(ModeRead); waitErr != nil {
+				if waitErr := s.pd.wait(ModeRead); waitErr != nil && !s.pd.broken() {
 					return 0, 0, waitErr
 				}
-				// Try one more time after waiting
+				// Try one more time after waiting, also if the connection
+				// broke, to collect the data left in the receive buffer
 				n, readErr = srtRecvMsg2Impl(s.socket, buffer[offset:], nil)
 			}
 
@@ -95,7 +195,10 @@ func (s SrtSocket) ReadBatch(buffer []byte, maxPackets int) (packetsRead int, to
 		}
 
 		if n == 0 {
-			// No more data available
+			// The peer closed a stream mode connection cleanly
+			if packetsRead == 0 {
+				return 0, 0, io.EOF
+			}
 			break
 		}
 
@@ -105,3 +208,168 @@ func (s SrtSocket) ReadBatch(buffer []byte, maxPackets int) (packetsRead int, to
 
 	return packetsRead, totalBytes, nil
 }
+
+// readReady reports whether a packet can be read without blocking, according
+// to the readiness SRT reports for epoll (SRTO_EVENT), which accounts for the
+// TSBPD delivery time
+func (s SrtSocket) readReady() bool {
+	events, err := s.GetSockOptInt(SRTO_EVENT)
+	return err == nil && events&C.SRT_EPOLL_IN != 0
+}
+
+// ReadBatchDeadline works like ReadBatch, but instead of returning as soon as no
+// more packets are immediately available it keeps collecting packets until
+// maxPackets have been read, the buffer is full or the deadline expires.
+// Packets collected before the deadline are returned without error; if none
+// arrived in time a SrtEpollTimeout error is returned. The deadline applies to
+// this call only; a read deadline set with SetReadDeadline still applies if it
+// is earlier.
+// Only available in non-blocking mode.
+func (s SrtSocket) ReadBatchDeadline(buffer []byte, maxPackets int, deadline time.Time) (packetsRead int, totalBytes int, err error) {
+	if s.blocking {
+		return 0, 0, errors.New("ReadBatchDeadline is only available in non-blocking mode")
+	}
+	if maxPackets <= 0 || len(buffer) == 0 {
+		return 0, 0, nil
+	}
+	defer s.guard(ModeRead)()
+
+	for packetsRead < maxPackets && totalBytes < len(buffer) {
+		n, readErr := srtRecvMsg2Impl(s.socket, buffer[totalBytes:], nil)
+		if readErr == nil {
+			if n == 0 {
+				// The peer closed a stream mode connection cleanly
+				if packetsRead == 0 {
+					return 0, 0, io.EOF
+				}
+				break
+			}
+			packetsRead++
+			totalBytes += n
+			continue
+		}
+
+		if errors.Is(readErr, error(EAsyncRCV)) {
+			s.pd.reset(ModeRead)
+			// The batch deadline bounds this call only, other readers keep
+			// the deadline of the socket
+			readErr = s.pd.waitUntil(deadline, ModeRead)
+			if readErr == nil || s.pd.broken() {
+				continue
+			}
+		}
+
+		// Deadline expired or connection error: return what we have
+		if packetsRead > 0 {
+			return packetsRead, totalBytes, nil
+		}
+		return 0, 0, readErr
+	}
+
+	return packetsRead, totalBytes, nil
+}
+
+// DrainRead returns the packets left in the receive buffer, in order, e.g. to
+// flush a receiver at shutdown. It reads until the buffer is empty or maxWait
+// has elapsed; packets that SRT holds back until their TSBPD delivery time are
+// waited for within maxWait. An empty buffer or an expired maxWait end the
+// drain with a nil error; if the connection is broken the packets read so far
+// are returned together with the error (EConnLost once the buffer is empty).
+// maxWait bounds this call only, the read deadline of the socket is not
+// changed and still applies. In blocking mode a read of a packet held for
+// TSBPD may exceed maxWait by up to the latency.
+func (s SrtSocket) DrainRead(maxWait time.Duration) ([][]byte, error) {
+	deadline := time.Now().Add(maxWait)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	var packets [][]byte
+	buf := make([]byte, s.readBufferSize())
+	for time.Now().Before(deadline) {
+		pending, err := s.GetSockOptInt(SRTO_RCVDATA)
+		if err != nil {
+			return packets, err
+		}
+		if pending == 0 {
+			if C.srt_getsockstate(s.socket) == C.SRTS_BROKEN {
+				return packets, EConnLost
+			}
+			return packets, nil
+		}
+		n, err := s.readContext(ctx, buf, nil)
+		if err != nil {
+			if IsTimeout(err) {
+				return packets, nil
+			}
+			return packets, err
+		}
+		packets = append(packets, append([]byte(nil), buf[:n]...))
+	}
+	return packets, nil
+}
+
+// readChannelPool recycles the packet buffers published by ReadChannel
+var readChannelPool sync.Pool
+
+// ReleasePacket returns a packet received from a ReadChannel channel to the
+// buffer pool once the consumer is done with it. The packet must not be used
+// afterwards. Releasing is optional: packets that are not released are
+// garbage collected, at the cost of an allocation per packet.
+func ReleasePacket(b []byte) {
+	b = b[:cap(b)]
+	readChannelPool.Put(&b)
+}
+
+// ReadChannel starts a goroutine that reads packets into pooled buffers and
+// publishes them on the returned packet channel, which holds up to bufSize
+// packets. Each packet is a whole message in message mode; consumers should
+// hand it back with ReleasePacket when done.
+//
+// When the channel is full the goroutine stops reading until the consumer
+// catches up, and packets accumulate in the SRT receive buffer: in live mode
+// SRT drops them once they are too late (tlpktdrop), in file mode flow control
+// slows down the sender.
+//
+// The goroutine ends when a read fails, which is reported on the error
+// channel, or when cancel is called; both channels are closed then. cancel
+// interrupts a pending read of a non-blocking socket, a blocking socket ends
+// after its current read returns.
+func (s SrtSocket) ReadChannel(bufSize int) (<-chan []byte, <-chan error, func()) {
+	packets := make(chan []byte, bufSize)
+	errs := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	size := s.readBufferSize()
+
+	go func() {
+		defer close(errs)
+		defer close(packets)
+		for ctx.Err() == nil {
+			var buf []byte
+			if bp, ok := readChannelPool.Get().(*[]byte); ok && cap(*bp) >= size {
+				buf = (*bp)[:size]
+			} else {
+				buf = make([]byte, size)
+			}
+
+			var n int
+			var err error
+			if s.blocking {
+				n, err = s.Read(buf)
+			} else {
+				n, err = s.ReadContext(ctx, buf)
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					errs <- err
+				}
+				return
+			}
+			select {
+			case packets <- buf[:n]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return packets, errs, cancel
+}
@@ -0,0 +1,244 @@
+package srtgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// RelayPolicy selects what a Relay does with a packet read while its buffer is full
+type RelayPolicy int
+
+const (
+	// RelayBlock - stop reading until the output catches up; no data is lost,
+	// which suits file transfers
+	RelayBlock RelayPolicy = iota
+	// RelayDropOldest - discard the oldest buffered packet, which keeps the
+	// latency of live streams bounded
+	RelayDropOldest
+)
+
+// defaultRelayDepth is the buffer depth used when none is configured
+const defaultRelayDepth = 256
+
+// RelayConfig configures a Relay
+type RelayConfig struct {
+	Depth  int         // packets buffered between input and output, 256 if zero
+	Policy RelayPolicy // what to do when the buffer is full
+}
+
+// RelayStats are the counters of a Relay
+type RelayStats struct {
+	Packets   uint64 // packets written to the output
+	Bytes     uint64 // bytes written to the output
+	Dropped   uint64 // packets discarded by the RelayDropOldest policy
+	HighWater uint64 // largest number of packets buffered at once
+}
+
+// relayIOFunc reads or writes one packet
+type relayIOFunc func(ctx context.Context, b []byte) (int, error)
+
+// Relay forwards packets from an input to an output socket through a bounded
+// ring buffer, with one goroutine reading and one writing, so that a brief
+// stall of the output does not stall the input. Packets are forwarded one
+// Read per Write, so message boundaries are preserved.
+//
+// The relay ends when the input fails (its buffered packets are still
+// written), when the output fails, or when Stop is called. The sockets are not
+// closed. Stop interrupts pending reads and writes of non-blocking sockets; on
+// blocking sockets it waits for them to return.
+type Relay struct {
+	// Accessed atomically, kept first for 64-bit alignment on 32-bit platforms
+	packets   uint64
+	bytes     uint64
+	dropped   uint64
+	highWater uint64
+
+	read, write relayIOFunc
+	policy      RelayPolicy
+	ctx         context.Context
+	cancel      context.CancelFunc
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	slots    [][]byte // ring buffer of packets, each with a capacity of bufSize
+	bufSize  int
+	head     int
+	count    int
+	readDone bool
+	stopped  bool
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// NewRelay starts relaying packets from in to out
+func NewRelay(in, out *SrtSocket, cfg RelayConfig) (*Relay, error) {
+	read := relayIOFunc(in.ReadContext)
+	if in.blocking {
+		read = func(_ context.Context, b []byte) (int, error) { return in.Read(b) }
+	}
+	write := relayIOFunc(out.WriteContext)
+	if out.writeBlocking {
+		write = func(_ context.Context, b []byte) (int, error) { return out.Write(b) }
+	}
+	return newRelay(read, write, in.readBufferSize(), cfg)
+}
+
+func newRelay(read, write relayIOFunc, bufSize int, cfg RelayConfig) (*Relay, error) {
+	if cfg.Depth < 0 {
+		return nil, fmt.Errorf("invalid relay depth %d", cfg.Depth)
+	}
+	if cfg.Policy != RelayBlock && cfg.Policy != RelayDropOldest {
+		return nil, fmt.Errorf("unknown relay policy %d", cfg.Policy)
+	}
+	depth := cfg.Depth
+	if depth == 0 {
+		depth = defaultRelayDepth
+	}
+
+	r := &Relay{
+		read:    read,
+		write:   write,
+		policy:  cfg.Policy,
+		slots:   make([][]byte, depth),
+		bufSize: bufSize,
+	}
+	for i := range r.slots {
+		r.slots[i] = make([]byte, bufSize)
+	}
+	r.notEmpty = sync.NewCond(&r.mu)
+	r.notFull = sync.NewCond(&r.mu)
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+
+	r.wg.Add(2)
+	go r.readLoop()
+	go r.writeLoop()
+	return r, nil
+}
+
+func (r *Relay) readLoop() {
+	defer r.wg.Done()
+	buf := make([]byte, r.bufSize)
+	for {
+		n, err := r.read(r.ctx, buf)
+		if err != nil {
+			r.fail(err)
+			r.mu.Lock()
+			r.readDone = true
+			r.notEmpty.Broadcast()
+			r.mu.Unlock()
+			return
+		}
+		if n == 0 {
+			continue
+		}
+
+		r.mu.Lock()
+		for r.count == len(r.slots) && !r.stopped {
+			if r.policy == RelayDropOldest {
+				r.head = (r.head + 1) % len(r.slots)
+				r.count--
+				atomic.AddUint64(&r.dropped, 1)
+				break
+			}
+			r.notFull.Wait()
+		}
+		if r.stopped {
+			r.mu.Unlock()
+			return
+		}
+		// Swap buffers with the free slot instead of copying
+		i := (r.head + r.count) % len(r.slots)
+		free := r.slots[i]
+		r.slots[i] = buf[:n]
+		buf = free[:cap(free)]
+		r.count++
+		if uint64(r.count) > atomic.LoadUint64(&r.highWater) {
+			atomic.StoreUint64(&r.highWater, uint64(r.count))
+		}
+		r.notEmpty.Signal()
+		r.mu.Unlock()
+	}
+}
+
+func (r *Relay) writeLoop() {
+	defer r.wg.Done()
+	buf := make([]byte, r.bufSize)
+	for {
+		r.mu.Lock()
+		for r.count == 0 && !r.readDone && !r.stopped {
+			r.notEmpty.Wait()
+		}
+		if r.stopped || r.count == 0 {
+			// Stopped, or the input ended and the buffer is drained
+			r.mu.Unlock()
+			return
+		}
+		packet := r.slots[r.head]
+		r.slots[r.head] = buf[:cap(buf)]
+		r.head = (r.head + 1) % len(r.slots)
+		r.count--
+		r.notFull.Signal()
+		r.mu.Unlock()
+		buf = packet
+
+		n, err := r.write(r.ctx, packet)
+		if err != nil {
+			r.fail(err)
+			r.stop()
+			return
+		}
+		atomic.AddUint64(&r.packets, 1)
+		atomic.AddUint64(&r.bytes, uint64(n))
+	}
+}
+
+// fail records the first error ending the relay. The end of the input and
+// the cancellation caused by Stop are not errors.
+func (r *Relay) fail(err error) {
+	if err == io.EOF || r.ctx.Err() != nil && err == r.ctx.Err() {
+		return
+	}
+	r.errOnce.Do(func() {
+		r.err = err
+	})
+}
+
+func (r *Relay) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	r.notEmpty.Broadcast()
+	r.notFull.Broadcast()
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// Stop ends the relay, discarding the packets still buffered, and waits for
+// its goroutines to return
+func (r *Relay) Stop() {
+	r.stop()
+	r.wg.Wait()
+}
+
+// Wait blocks until the relay has ended and returns the error that ended it,
+// or nil if it was stopped or the input reached EOF
+func (r *Relay) Wait() error {
+	r.wg.Wait()
+	r.cancel()
+	return r.err
+}
+
+// Stats returns the counters of the relay
+func (r *Relay) Stats() RelayStats {
+	return RelayStats{
+		Packets:   atomic.LoadUint64(&r.packets),
+		Bytes:     atomic.LoadUint64(&r.bytes),
+		Dropped:   atomic.LoadUint64(&r.dropped),
+		HighWater: atomic.LoadUint64(&r.highWater),
+	}
+}
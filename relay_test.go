@@ -0,0 +1,156 @@
+package srtgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRelayIO feeds packets from a channel and records written packets,
+// with writes held until the gate is opened
+type fakeRelayIO struct {
+	in   chan []byte
+	gate chan struct{}
+
+	mu      sync.Mutex
+	written [][]byte
+}
+
+func newFakeRelayIO() *fakeRelayIO {
+	return &fakeRelayIO{in: make(chan []byte), gate: make(chan struct{})}
+}
+
+func (f *fakeRelayIO) read(ctx context.Context, b []byte) (int, error) {
+	select {
+	case p, ok := <-f.in:
+		if !ok {
+			return 0, io.EOF
+		}
+		return copy(b, p), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (f *fakeRelayIO) write(ctx context.Context, b []byte) (int, error) {
+	select {
+	case <-f.gate:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	f.mu.Lock()
+	f.written = append(f.written, append([]byte(nil), b...))
+	f.mu.Unlock()
+	return len(b), nil
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRelayDropOldest(t *testing.T) {
+	f := newFakeRelayIO()
+	r, err := newRelay(f.read, f.write, 16, RelayConfig{Depth: 4, Policy: RelayDropOldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The writer holds packet 0 while packets 1-9 fill the buffer
+	f.in <- []byte{0}
+	waitFor(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.Stats().HighWater == 1 && r.count == 0
+	})
+	for i := 1; i < 10; i++ {
+		f.in <- []byte{byte(i)}
+	}
+	waitFor(t, func() bool { return r.Stats().Dropped == 5 })
+	close(f.gate)
+	close(f.in)
+	if err := r.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0, 6, 7, 8, 9}
+	if len(f.written) != len(expected) {
+		t.Fatalf("expected %d packets written, got %d", len(expected), len(f.written))
+	}
+	for i, p := range f.written {
+		if p[0] != expected[i] {
+			t.Errorf("packet %d: expected %d, got %d", i, expected[i], p[0])
+		}
+	}
+	stats := r.Stats()
+	if stats.Packets != 5 || stats.Bytes != 5 || stats.HighWater != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRelayBlock(t *testing.T) {
+	f := newFakeRelayIO()
+	r, err := newRelay(f.read, f.write, 16, RelayConfig{Depth: 2, Policy: RelayBlock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			f.in <- []byte{byte(i)}
+		}
+		close(f.in)
+	}()
+	waitFor(t, func() bool { return r.Stats().HighWater == 2 })
+	close(f.gate)
+	if err := r.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.written) != 5 {
+		t.Fatalf("expected 5 packets written, got %d", len(f.written))
+	}
+	for i, p := range f.written {
+		if p[0] != byte(i) {
+			t.Errorf("packet %d: got %d", i, p[0])
+		}
+	}
+	if stats := r.Stats(); stats.Dropped != 0 || stats.HighWater != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRelayStopAndErrors(t *testing.T) {
+	f := newFakeRelayIO()
+	r, err := newRelay(f.read, f.write, 16, RelayConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.in <- []byte{1}
+	r.Stop()
+	if err := r.Wait(); err != nil {
+		t.Errorf("stopped relay should not report an error, got %v", err)
+	}
+
+	writeErr := errors.New("output failed")
+	r, err = newRelay(f.read, func(context.Context, []byte) (int, error) { return 0, writeErr }, 16, RelayConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.in <- []byte{1}
+	if err := r.Wait(); err != writeErr {
+		t.Errorf("expected output error, got %v", err)
+	}
+
+	if _, err := newRelay(f.read, f.write, 16, RelayConfig{Policy: RelayPolicy(7)}); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,149 @@
+package srtgo
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+// RouteHandler handles a connection dispatched by a Router, together with
+// the parsed streamid the caller connected with. The socket is closed when
+// the handler returns.
+type RouteHandler func(socket *SrtSocket, id StreamID)
+
+// RouteWildcard matches any user or resource in Router.Handle
+const RouteWildcard = "*"
+
+type route struct {
+	user     string
+	resource string
+	handler  RouteHandler
+}
+
+// Router dispatches the connections of a listener to handlers chosen by the
+// user and resource of their streamid (see ParseStreamID), the routing layer
+// of an SRT gateway. Connections without a matching route are rejected
+// during the handshake with RejectionReasonNotFound, and ones with a
+// malformed streamid with RejectionReasonBadRequest, so they never reach
+// Accept. Routes can be added while the router serves.
+type Router struct {
+	mu     sync.RWMutex
+	routes []route
+}
+
+// NewRouter creates a router without routes
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers handler for the streamids with the given user and
+// resource. RouteWildcard matches any value, including an empty one, and a
+// resource ending in "*" matches every resource with that prefix, e.g.
+// "live/*". When several routes match, the one with the most specific
+// resource wins (exact, then the longest prefix, then the wildcard), and among
+// those a route for the exact user wins over one for any user. Registering
+// the same user and resource again replaces the handler.
+func (r *Router) Handle(user, resource string, handler RouteHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.routes {
+		if r.routes[i].user == user && r.routes[i].resource == resource {
+			r.routes[i].handler = handler
+			return
+		}
+	}
+	r.routes = append(r.routes, route{user: user, resource: resource, handler: handler})
+}
+
+// HandleDefault registers the handler for connections no other route
+// matches, the same as Handle(RouteWildcard, RouteWildcard, handler)
+func (r *Router) HandleDefault(handler RouteHandler) {
+	r.Handle(RouteWildcard, RouteWildcard, handler)
+}
+
+// resourceScore ranks how specifically pattern matches resource, -1 if it
+// does not match at all
+func resourceScore(pattern, resource string) int {
+	switch {
+	case pattern == RouteWildcard:
+		return 0
+	case strings.HasSuffix(pattern, "*"):
+		prefix := strings.TrimSuffix(pattern, "*")
+		if !strings.HasPrefix(resource, prefix) {
+			return -1
+		}
+		// Any prefix, even an empty one, ranks above the wildcard
+		return 1 + len(prefix)
+	case pattern == resource:
+		// Above any prefix of a streamid within maxStreamIDLen
+		return 2 + maxStreamIDLen
+	default:
+		return -1
+	}
+}
+
+// Lookup returns the handler of the route matching id, or nil
+func (r *Router) Lookup(id StreamID) RouteHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var best RouteHandler
+	bestScore := -1
+	for _, rt := range r.routes {
+		score := resourceScore(rt.resource, id.Resource)
+		if score < 0 {
+			continue
+		}
+		score *= 2
+		if rt.user == id.User {
+			score++
+		} else if rt.user != RouteWildcard {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = rt.handler, score
+		}
+	}
+	return best
+}
+
+// admit is the listen callback of ListenAndServe
+func (r *Router) admit(socket *SrtSocket, version int, addr *net.UDPAddr, streamid string) bool {
+	id, err := ParseStreamID(streamid)
+	if err != nil {
+		socket.SetRejectReason(RejectionReasonBadRequest)
+		return false
+	}
+	if r.Lookup(id) == nil {
+		socket.SetRejectReason(RejectionReasonNotFound)
+		return false
+	}
+	return true
+}
+
+// ListenAndServe installs a listen callback on listener that rejects
+// unmatched connections during the handshake, replacing any callback set
+// before, starts listening with the given backlog, and then accepts the
+// connections like SrtSocket.Serve, with at most maxConcurrent handlers
+// running at once. Each connection runs the handler its streamid is routed to
+// at dispatch time. It returns once the listener is closed.
+func (r *Router) ListenAndServe(listener *SrtSocket, backlog, maxConcurrent int) error {
+	if err := listener.SetListenCallback(r.admit); err != nil {
+		return err
+	}
+	if err := listener.Listen(backlog); err != nil {
+		return err
+	}
+	return listener.Serve(func(socket *SrtSocket) {
+		streamid, err := socket.GetSockOptString(SRTO_STREAMID)
+		if err != nil {
+			return
+		}
+		id, err := ParseStreamID(streamid)
+		if err != nil {
+			return
+		}
+		if handler := r.Lookup(id); handler != nil {
+			handler(socket, id)
+		}
+	}, maxConcurrent)
+}
@@ -0,0 +1,110 @@
+package srtgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRouterLookup(t *testing.T) {
+	var got string
+	handler := func(name string) RouteHandler {
+		return func(*SrtSocket, StreamID) { got = name }
+	}
+	r := NewRouter()
+	r.Handle(RouteWildcard, "live/cam1", handler("exact"))
+	r.Handle("admin", "live/cam1", handler("exact-user"))
+	r.Handle(RouteWildcard, "live/*", handler("prefix"))
+	r.Handle(RouteWildcard, "live/hd/*", handler("longer-prefix"))
+
+	tests := []struct {
+		user, resource string
+		expected       string
+	}{
+		{"", "live/cam1", "exact"},
+		{"admin", "live/cam1", "exact-user"},
+		{"", "live/cam2", "prefix"},
+		{"", "live/hd/cam1", "longer-prefix"},
+		{"", "vod/movie", ""},
+	}
+	for _, tt := range tests {
+		got = ""
+		h := r.Lookup(StreamID{User: tt.user, Resource: tt.resource})
+		if h != nil {
+			h(nil, StreamID{})
+		}
+		if got != tt.expected {
+			t.Errorf("%s/%s: expected route %q, got %q", tt.user, tt.resource, tt.expected, got)
+		}
+	}
+
+	r.HandleDefault(handler("default"))
+	got = ""
+	if h := r.Lookup(StreamID{Resource: "vod/movie"}); h != nil {
+		h(nil, StreamID{})
+	}
+	if got != "default" {
+		t.Errorf("expected the default route, got %q", got)
+	}
+}
+
+func TestRouterListenAndServe(t *testing.T) {
+	InitSRT()
+
+	port := randomPort()
+	listener := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": "1", "mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+
+	routed := make(chan StreamID, 1)
+	r := NewRouter()
+	r.Handle(RouteWildcard, "live/*", func(s *SrtSocket, id StreamID) { routed <- id })
+	served := make(chan error, 1)
+	go func() { served <- r.ListenAndServe(listener, 2, 4) }()
+	time.Sleep(100 * time.Millisecond)
+
+	connect := func(streamid string) (*SrtSocket, error) {
+		caller := NewSrtSocket("127.0.0.1", port, map[string]string{
+			"blocking": "1", "mode": "caller", "conntimeo": "1000", "streamid": streamid,
+		})
+		if caller == nil {
+			t.Fatal("Could not create a srt socket")
+		}
+		return caller, caller.Connect()
+	}
+
+	caller, err := connect("#!::u=alice,r=live/cam1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-routed:
+		if id.User != "alice" || id.Resource != "live/cam1" {
+			t.Errorf("unexpected streamid %+v", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection was not routed")
+	}
+	caller.Close()
+
+	caller, err = connect("#!::r=vod/movie")
+	if err == nil {
+		t.Error("expected an unmatched streamid to be rejected")
+	}
+	caller.Close()
+	select {
+	case id := <-routed:
+		t.Errorf("unmatched streamid %+v was routed", id)
+	default:
+	}
+
+	listener.Close()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("ListenAndServe returned %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("ListenAndServe did not return after closing the listener")
+	}
+}
@@ -1,9 +1,18 @@
 package srtgo
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -120,3 +129,1387 @@ func BenchmarkRWBlocking(b *testing.B) {
 func BenchmarkRWNonBlocking(b *testing.B) {
 	runTransmitBench(b, false)
 }
+
+// Creates a connected caller/listener pair, returns the caller and the accepted socket
+func connectedPair(t *testing.T, options map[string]string) (*SrtSocket, *SrtSocket) {
+	InitSRT()
+	caller, remote, err := pipe(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return caller, remote
+}
+
+func TestReadBatchDeadline(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	payload := make([]byte, 100)
+	for i := 0; i < 3; i++ {
+		if _, err := caller.Write(payload); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	buf := make([]byte, 100*10)
+	packets, total, err := remote.ReadBatchDeadline(buf, 10, time.Now().Add(500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected partial batch without error, got %v", err)
+	}
+	if packets != 3 || total != 300 {
+		t.Errorf("expected 3 packets / 300 bytes, got %d / %d", packets, total)
+	}
+
+	start := time.Now()
+	packets, _, err = remote.ReadBatchDeadline(buf, 10, time.Now().Add(50*time.Millisecond))
+	if packets != 0 {
+		t.Errorf("expected no packets, got %d", packets)
+	}
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if terr, ok := err.(interface{ Timeout() bool }); !ok || !terr.Timeout() {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("ReadBatchDeadline did not honor the deadline")
+	}
+
+	// The batch deadline must not be applied to the socket
+	if !remote.pd.deadline(ModeRead).IsZero() {
+		t.Error("read deadline of the socket was changed")
+	}
+
+	// An earlier socket deadline still applies
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	start = time.Now()
+	if _, _, err = remote.ReadBatchDeadline(buf, 10, time.Now().Add(5*time.Second)); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("ReadBatchDeadline did not honor the socket deadline")
+	}
+}
+
+func TestReadBatchReturnsWhenIdle(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"blocking": "1", "transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	// Bounds the test should ReadBatch block after the available packets
+	if err := remote.SetReceiveTimeout(2 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, 100)
+	for i := 0; i < 3; i++ {
+		if _, err := caller.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	buf := make([]byte, 100*10)
+	start := time.Now()
+	packets, total, err := remote.ReadBatch(buf, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packets != 3 || total != 300 {
+		t.Errorf("expected 3 packets / 300 bytes, got %d / %d", packets, total)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ReadBatch waited %s after the available packets", elapsed)
+	}
+}
+
+func TestReceiveTimeoutBlocking(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"blocking": "1", "transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if err := remote.SetReceiveTimeout(100 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1500)
+	start := time.Now()
+	_, err := remote.Read(buf)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if terr, ok := err.(interface{ Timeout() bool }); !ok || !terr.Timeout() {
+		t.Errorf("expected error with Timeout() == true, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("read did not honor rcvtimeo")
+	}
+}
+
+func TestWriteMessageReassembly(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	msg := make([]byte, 5000)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, size := range []int{0, 10, len(msg)} {
+		n, err := caller.WriteMessage(msg[:size])
+		if err != nil {
+			t.Fatalf("write message: %v", err)
+		}
+		if n != size {
+			t.Errorf("expected %d bytes written, got %d", size, n)
+		}
+
+		got, err := remote.ReadMessage()
+		if err != nil {
+			t.Fatalf("read message: %v", err)
+		}
+		if !bytes.Equal(got, msg[:size]) {
+			t.Errorf("message of %d bytes was not reassembled correctly", size)
+		}
+	}
+
+	maxSize, err := caller.MaxMessageSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := caller.WriteMessage(make([]byte, maxSize+1)); !errors.Is(err, ELargeMsg) {
+		t.Errorf("expected ELargeMsg for oversized message, got %v", err)
+	}
+}
+
+func TestWriteMessageNative(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "1"})
+	defer caller.Close()
+	defer remote.Close()
+
+	msg := make([]byte, 5000)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	if n, err := caller.WriteMessage(msg); err != nil || n != len(msg) {
+		t.Fatalf("expected %d bytes written, got %d (%v)", len(msg), n, err)
+	}
+	// Sent as one SRT message without framing, so any reader gets it whole
+	remote.SetReadDeadline(time.Now().Add(2 * time.Second))
+	got, err := remote.ReadWholeMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("expected the message unchanged, got %d bytes", len(got))
+	}
+
+	if _, err := caller.Write(msg[:100]); err != nil {
+		t.Fatal(err)
+	}
+	got, err = remote.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, msg[:100]) {
+		t.Errorf("expected a plain SRT message to be read whole, got %d bytes", len(got))
+	}
+
+	l, err := caller.messageLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxSize, err := caller.MaxMessageSize(); err != nil || maxSize != l.max {
+		t.Errorf("expected the SRT message limit %d, got %d (%v)", l.max, maxSize, err)
+	}
+}
+
+func TestWriteMessageStreamMode(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if _, err := caller.WriteMessage([]byte("framed")); err == nil {
+		t.Error("expected WriteMessage to fail in stream mode")
+	}
+	if _, err := remote.ReadMessage(); err == nil {
+		t.Error("expected ReadMessage to fail in stream mode")
+	}
+}
+
+func TestWriteV(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	header := []byte{0x47, 0x01, 0x02}
+	payload := bytes.Repeat([]byte{0xab}, 184)
+	n, err := caller.WriteV(header, nil, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(header)+len(payload) {
+		t.Errorf("expected %d bytes written, got %d", len(header)+len(payload), n)
+	}
+
+	buf := make([]byte, 1500)
+	n, err = remote.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], append(header, payload...)) {
+		t.Error("gathered message does not match the input buffers")
+	}
+
+	if _, err := caller.WriteV(header, make([]byte, 1500)); !errors.Is(err, ELargeMsg) {
+		t.Errorf("expected ELargeMsg for oversized message, got %v", err)
+	}
+}
+
+func TestReadDeadlineExceeded(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err := remote.Read(make([]byte, 1500))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected os.ErrDeadlineExceeded, got %v", err)
+	}
+	if terr, ok := err.(interface{ Timeout() bool }); !ok || !terr.Timeout() {
+		t.Errorf("expected error with Timeout() true, got %v", err)
+	}
+}
+
+func TestSetBlocking(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"blocking": "1", "transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	// Data that arrived in blocking mode is readable after the switch
+	payload := []byte("sent while blocking")
+	if _, err := caller.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := remote.SetBlocking(false, false); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1500)
+	remote.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := remote.Read(buf); err != nil || !bytes.Equal(buf[:n], payload) {
+		t.Fatalf("expected %q, got %q (%v)", payload, buf[:n], err)
+	}
+
+	// Non-blocking reads now honour deadlines
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := remote.Read(buf); !IsTimeout(err) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	remote.SetReadDeadline(time.Time{})
+
+	// Mixed mode: non-blocking reads, blocking writes
+	if err := caller.SetBlocking(false, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := caller.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := remote.Read(buf); err != nil || !bytes.Equal(buf[:n], payload) {
+		t.Fatalf("expected %q, got %q (%v)", payload, buf[:n], err)
+	}
+
+	// Back to blocking mode
+	if err := remote.SetBlocking(true, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.ReadContext(context.Background(), buf); err == nil {
+		t.Error("expected ReadContext to reject a blocking socket")
+	}
+}
+
+func TestDeadlineGetters(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if !remote.ReadDeadline().IsZero() || !remote.WriteDeadline().IsZero() {
+		t.Fatal("expected no deadlines on a new socket")
+	}
+	deadline := time.Now().Add(time.Hour)
+	remote.SetReadDeadline(deadline)
+	if got := remote.ReadDeadline(); !got.Equal(deadline) {
+		t.Errorf("expected read deadline %v, got %v", deadline, got)
+	}
+	if !remote.WriteDeadline().IsZero() {
+		t.Error("setting the read deadline must not affect the write deadline")
+	}
+	remote.SetDeadline(time.Time{})
+	if !remote.ReadDeadline().IsZero() {
+		t.Error("expected the read deadline to be cleared")
+	}
+}
+
+func TestCloseWrite(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	payload := []byte("last words")
+	if _, err := caller.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	// Live mode does not linger, the write deadline bounds the wait for the
+	// acknowledgement instead
+	caller.SetWriteDeadline(time.Now().Add(2 * time.Second))
+	if err := caller.CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	var closedErr *SrtWriteClosed
+	if _, err := caller.Write(payload); !errors.As(err, &closedErr) {
+		t.Errorf("expected SrtWriteClosed after CloseWrite, got %v", err)
+	}
+
+	// Data written before CloseWrite is delivered
+	buf := make([]byte, 1500)
+	n, err := remote.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], payload) {
+		t.Errorf("expected %q, got %q", payload, buf[:n])
+	}
+
+	// The read side of the half-closed socket keeps working
+	if _, err := remote.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	n, err = caller.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], payload) {
+		t.Errorf("expected %q, got %q", payload, buf[:n])
+	}
+}
+
+type collectingMessageWriter struct {
+	mu   sync.Mutex
+	msgs [][]byte
+}
+
+func (c *collectingMessageWriter) WriteMessage(b []byte, ctrl *MsgCtrl) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, append([]byte(nil), b...))
+	return nil
+}
+
+func (c *collectingMessageWriter) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.msgs)
+}
+
+func TestReadMessagesTo(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	w := &collectingMessageWriter{}
+	stop := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- remote.ReadMessagesTo(w, stop)
+	}()
+
+	for i := 0; i < 3; i++ {
+		if _, err := caller.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for start := time.Now(); w.count() < 3; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("expected 3 messages, got %d", w.count())
+		}
+	}
+
+	close(stop)
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("expected clean stop, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ReadMessagesTo did not stop")
+	}
+	for i, msg := range w.msgs {
+		if !bytes.Equal(msg, []byte{byte(i)}) {
+			t.Errorf("message %d: unexpected content %v", i, msg)
+		}
+	}
+	if !remote.pd.deadline(ModeRead).IsZero() {
+		t.Error("read deadline of the socket was changed")
+	}
+
+	// Stopping leaves the socket readable
+	if _, err := caller.Write([]byte{3}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1316)
+	if n, err := remote.Read(buf); err != nil || n != 1 || buf[0] != 3 {
+		t.Errorf("read after stop: got %v (%d bytes), %v", buf[:n], n, err)
+	}
+}
+
+func TestPipe(t *testing.T) {
+	InitSRT()
+	a, b, err := Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	defer b.Close()
+
+	buf := make([]byte, 1500)
+	for _, dir := range [][2]*SrtSocket{{a, b}, {b, a}} {
+		if _, err := dir[0].Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		n, err := dir[1].Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != "hello" {
+			t.Errorf("expected hello, got %q", buf[:n])
+		}
+	}
+}
+
+func TestDrainRead(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer remote.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := caller.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packets, err := remote.DrainRead(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 5 {
+		t.Fatalf("expected 5 packets, got %d", len(packets))
+	}
+	for i, p := range packets {
+		if !bytes.Equal(p, []byte{byte(i)}) {
+			t.Errorf("packet %d out of order: %v", i, p)
+		}
+	}
+
+	// Empty buffer returns immediately without error
+	start := time.Now()
+	packets, err = remote.DrainRead(time.Second)
+	if err != nil || len(packets) != 0 {
+		t.Errorf("expected empty drain, got %d packets, %v", len(packets), err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("draining an empty buffer should not wait")
+	}
+	if d := remote.ReadDeadline(); !d.IsZero() {
+		t.Errorf("DrainRead changed the read deadline to %v", d)
+	}
+
+	// A broken connection is reported
+	caller.Close()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		_, err := remote.DrainRead(time.Second)
+		if err != nil {
+			if !IsConnectionBroken(err) {
+				t.Errorf("expected connection broken error, got %v", err)
+			}
+			break
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Fatal("broken connection was reported as an empty buffer")
+		}
+	}
+}
+
+func TestReadStreamMode(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "0"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if messageAPI, err := remote.MessageAPI(); err != nil || messageAPI {
+		t.Fatalf("expected stream mode, got messageapi=%t (%v)", messageAPI, err)
+	}
+
+	payload := []byte("hello, stream world")
+	if _, err := caller.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	// Short reads return the stream in pieces no larger than the buffer
+	var got []byte
+	buf := make([]byte, 4)
+	remote.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for len(got) < len(payload) {
+		n, err := remote.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > len(buf) {
+			t.Fatalf("read %d bytes into a %d byte buffer", n, len(buf))
+		}
+		got = append(got, buf[:n]...)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("expected %q, got %q", payload, got)
+	}
+}
+
+func TestCloseWriteBounded(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	for i := 0; i < 100; i++ {
+		if _, err := caller.Write(make([]byte, 1316)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// With an expired write deadline CloseWrite does not wait for the peer
+	caller.SetWriteDeadline(time.Now())
+	start := time.Now()
+	caller.CloseWrite()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CloseWrite waited %v past the write deadline", elapsed)
+	}
+	var closedErr *SrtWriteClosed
+	if _, err := caller.Write([]byte("late")); !errors.As(err, &closedErr) {
+		t.Errorf("expected SrtWriteClosed after CloseWrite, got %v", err)
+	}
+}
+
+func TestReadEOFOnCleanClose(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "0"})
+	defer remote.Close()
+
+	payload := []byte("everything before the close")
+	if _, err := caller.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := caller.CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	caller.Close()
+
+	// io.Copy ends with the data sent before the close and without an error
+	remote.SetReadDeadline(time.Now().Add(3 * time.Second))
+	var got bytes.Buffer
+	if _, err := io.Copy(&got, remote); err != nil {
+		t.Fatalf("expected a clean end of stream, got %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), payload) {
+		t.Errorf("expected %q, got %q", payload, got.Bytes())
+	}
+	if _, err := remote.Read(make([]byte, 16)); err != io.EOF {
+		t.Errorf("expected io.EOF after the end of stream, got %v", err)
+	}
+}
+
+// udpForwarder relays datagrams between a caller and a listener on the
+// loopback interface, so that a test can cut the path without either side
+// sending a shutdown
+type udpForwarder struct {
+	conn   *net.UDPConn
+	target *net.UDPAddr
+	cut    int32
+	mu     sync.Mutex
+	client *net.UDPAddr
+}
+
+func newUDPForwarder(t *testing.T, targetPort uint16) *udpForwarder {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(pipeHost)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &udpForwarder{conn: conn, target: &net.UDPAddr{IP: net.ParseIP(pipeHost), Port: int(targetPort)}}
+	go f.run()
+	return f
+}
+
+func (f *udpForwarder) port() uint16 {
+	return uint16(f.conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func (f *udpForwarder) run() {
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := f.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if atomic.LoadInt32(&f.cut) != 0 {
+			continue
+		}
+		to := f.target
+		if from.Port == f.target.Port {
+			f.mu.Lock()
+			to = f.client
+			f.mu.Unlock()
+		} else {
+			f.mu.Lock()
+			f.client = from
+			f.mu.Unlock()
+		}
+		if to != nil {
+			f.conn.WriteToUDP(buf[:n], to)
+		}
+	}
+}
+
+func TestReadConnectionLost(t *testing.T) {
+	InitSRT()
+	opts := map[string]string{"transtype": "file", "messageapi": "0", "peeridletimeo": "1000"}
+	lopts := map[string]string{"mode": "listener"}
+	copts := map[string]string{"mode": "caller"}
+	for k, v := range opts {
+		lopts[k] = v
+		copts[k] = v
+	}
+	listener := NewSrtSocket(pipeHost, 0, lopts)
+	if listener == nil {
+		t.Fatal("failed to create listener socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwarder := newUDPForwarder(t, port)
+	defer forwarder.conn.Close()
+
+	caller := NewSrtSocket(pipeHost, forwarder.port(), copts)
+	if caller == nil {
+		t.Fatal("failed to create caller socket")
+	}
+	defer caller.Close()
+	connErr := make(chan error, 1)
+	go func() { connErr <- caller.Connect() }()
+	remote, _, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	if err := <-connErr; err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte("before the path is cut")
+	if _, err := caller.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	remote.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if n, err := remote.Read(buf); err != nil || !bytes.Equal(buf[:n], payload) {
+		t.Fatalf("expected %q, got %q (%v)", payload, buf[:n], err)
+	}
+
+	// Without a shutdown from the peer the connection times out, which is
+	// not a clean end of stream
+	atomic.StoreInt32(&forwarder.cut, 1)
+	_, err = remote.Read(buf)
+	if err == nil || err == io.EOF {
+		t.Fatalf("expected a connection error, got %v", err)
+	}
+	if !IsConnectionBroken(err) {
+		t.Errorf("expected a broken connection error, got %v", err)
+	}
+}
+
+func TestWriteMsgOrdering(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "1"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if ok, err := remote.OutOfOrderDelivery(); err != nil || !ok {
+		t.Errorf("expected out-of-order delivery in file message mode, got %t (%v)", ok, err)
+	}
+
+	ctrl := NewMsgCtrl()
+	var msgNos []int32
+	for i := 0; i < 5; i++ {
+		ctrl.InOrder = i%2 == 0
+		if _, err := caller.WriteMsg([]byte{byte(i)}, ctrl); err != nil {
+			t.Fatal(err)
+		}
+		msgNos = append(msgNos, ctrl.MsgNo)
+	}
+
+	// Without loss out-of-order messages arrive in sending order as well
+	buf := make([]byte, 1500)
+	rctrl := NewMsgCtrl()
+	remote.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for i := 0; i < 5; i++ {
+		n, err := remote.ReadInto(buf, rctrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 || buf[0] != byte(i) {
+			t.Fatalf("message %d: got %v", i, buf[:n])
+		}
+		if rctrl.MsgNo != msgNos[i] {
+			t.Errorf("message %d: expected msgno %d, got %d", i, msgNos[i], rctrl.MsgNo)
+		}
+	}
+
+	live, liveRemote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer live.Close()
+	defer liveRemote.Close()
+	if ok, err := live.OutOfOrderDelivery(); err != nil || ok {
+		t.Errorf("expected in-order delivery in live mode, got %t (%v)", ok, err)
+	}
+
+	stream, streamRemote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "0"})
+	defer stream.Close()
+	defer streamRemote.Close()
+	if _, err := stream.WriteMsg([]byte("x"), ctrl); err == nil {
+		t.Error("expected WriteMsg to fail in stream mode")
+	}
+}
+
+func TestPing(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	// The responder passes application messages through and answers pings
+	responder := NewPongResponder(remote)
+	received := make(chan []byte, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, err := responder.Read(buf)
+			if err != nil {
+				return
+			}
+			received <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+
+	rtt, err := caller.Ping(2 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rtt <= 0 || rtt > 2*time.Second {
+		t.Errorf("unexpected round-trip time %s", rtt)
+	}
+
+	payload := []byte("media")
+	if _, err := caller.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-received:
+		if !bytes.Equal(msg, payload) {
+			t.Errorf("expected %q, got %q", payload, msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("application message not delivered by the responder")
+	}
+
+	// Without a responder the ping times out
+	if _, err := remote.Ping(200 * time.Millisecond); !IsTimeout(err) {
+		t.Errorf("expected a timeout without a responder, got %v", err)
+	}
+	if d := remote.ReadDeadline(); !d.IsZero() {
+		t.Errorf("Ping changed the read deadline to %v", d)
+	}
+}
+
+func TestReadMessageMode(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "1"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if messageAPI, err := remote.MessageAPI(); err != nil || !messageAPI {
+		t.Fatalf("expected message mode, got messageapi=%t (%v)", messageAPI, err)
+	}
+
+	messages := [][]byte{[]byte("first"), []byte("second message")}
+	for _, msg := range messages {
+		if _, err := caller.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each read returns exactly one message, even with room for more
+	buf := make([]byte, 1500)
+	for _, msg := range messages {
+		n, err := remote.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf[:n], msg) {
+			t.Errorf("expected %q, got %q", msg, buf[:n])
+		}
+	}
+}
+
+func TestReadWholeMessage(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "1"})
+	defer caller.Close()
+	defer remote.Close()
+
+	// Larger than a single packet, so a payload-sized buffer would lose it
+	msg := bytes.Repeat([]byte("0123456789"), 1000)
+	if _, err := caller.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := remote.ReadWholeMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("expected %d byte message, got %d bytes", len(msg), len(got))
+	}
+
+	stream, streamRemote := connectedPair(t, map[string]string{"transtype": "file"})
+	defer stream.Close()
+	defer streamRemote.Close()
+	if _, err := streamRemote.ReadWholeMessage(); err == nil {
+		t.Error("expected an error in stream mode")
+	}
+}
+
+func TestMessageLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options map[string]string
+	}{
+		{"live", map[string]string{"transtype": "live"}},
+		{"stream", map[string]string{"transtype": "file"}},
+		{"file message", map[string]string{"transtype": "file", "messageapi": "1"}},
+	} {
+		caller, remote := connectedPair(t, tc.options)
+		l, err := remote.messageLimits()
+		caller.Close()
+		remote.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if l.payload <= 0 {
+			t.Errorf("%s: invalid payload size %d", tc.name, l.payload)
+		}
+		switch tc.name {
+		case "live":
+			if l.max != l.payload || l.read != l.payload {
+				t.Errorf("%s: expected messages of one payload, got %+v", tc.name, l)
+			}
+		case "stream":
+			if l.max != 0 || l.read != l.payload {
+				t.Errorf("%s: expected no message bound, got %+v", tc.name, l)
+			}
+		default:
+			if l.max <= l.payload || l.read != l.max {
+				t.Errorf("%s: expected messages spanning packets, got %+v", tc.name, l)
+			}
+		}
+	}
+}
+
+func TestFramedReader(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	const packets = 5
+	stream := make([]byte, packets*TSPacketSize)
+	for i := range stream {
+		stream[i] = byte(i / TSPacketSize)
+	}
+	// Message boundaries deliberately do not match packet boundaries
+	for _, size := range []int{300, 300, 340} {
+		if _, err := caller.Write(stream[:size]); err != nil {
+			t.Fatal(err)
+		}
+		stream = stream[size:]
+	}
+
+	scanner := remote.FramedReader(ScanTSPackets)
+	for i := 0; i < packets; i++ {
+		if !scanner.Scan() {
+			t.Fatalf("scan of packet %d failed: %v", i, scanner.Err())
+		}
+		frame := scanner.Bytes()
+		if !bytes.Equal(frame, bytes.Repeat([]byte{byte(i)}, TSPacketSize)) {
+			t.Errorf("packet %d was not reassembled correctly", i)
+		}
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	buf := make([]byte, 1500)
+	if _, err := remote.ReadContext(ctx, buf); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// The socket must still be usable after a cancelled read
+	if _, err := caller.Write([]byte("after")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	n, err := remote.ReadContext(ctx, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "after" {
+		t.Errorf("unexpected payload %q", buf[:n])
+	}
+}
+
+func TestReadInto(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	const messages = 3
+	for i := 0; i < messages; i++ {
+		if _, err := caller.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// One MsgCtrl is reused for every read and refilled each time
+	buf := make([]byte, 1500)
+	ctrl := NewMsgCtrl()
+	var prev MsgCtrl
+	remote.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for i := 0; i < messages; i++ {
+		n, err := remote.ReadInto(buf, ctrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 || buf[0] != byte(i) {
+			t.Fatalf("message %d: got %v", i, buf[:n])
+		}
+		if ctrl.SrcTime == 0 {
+			t.Errorf("message %d: expected a source time", i)
+		}
+		if i > 0 {
+			// Every message fits one packet
+			if ctrl.MsgNo != prev.MsgNo+1 {
+				t.Errorf("message %d: expected msgno %d, got %d", i, prev.MsgNo+1, ctrl.MsgNo)
+			}
+			if ctrl.PktSeq != (prev.PktSeq+1)&0x7FFFFFFF {
+				t.Errorf("message %d: expected packet sequence %d, got %d", i, prev.PktSeq+1, ctrl.PktSeq)
+			}
+		}
+		prev = *ctrl
+	}
+
+	// A nil MsgCtrl reads like Read
+	if _, err := caller.Write([]byte("plain")); err != nil {
+		t.Fatal(err)
+	}
+	n, err := remote.ReadInto(buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "plain" {
+		t.Errorf("unexpected payload %q", buf[:n])
+	}
+
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err = remote.ReadInto(buf, ctrl)
+	if terr, ok := err.(interface{ Timeout() bool }); !ok || !terr.Timeout() {
+		t.Errorf("expected error with Timeout() true, got %v", err)
+	}
+}
+
+func TestReadWriteString(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if n, err := caller.WriteString(""); n != 0 || err != nil {
+		t.Errorf("expected an empty string to send nothing, got %d (%v)", n, err)
+	}
+	if n, err := caller.WriteString("hello"); n != 5 || err != nil {
+		t.Fatalf("expected 5 bytes written, got %d (%v)", n, err)
+	}
+	remote.SetReadDeadline(time.Now().Add(time.Second))
+	if str, err := remote.ReadString(0); str != "" || err != nil {
+		t.Errorf("expected ReadString(0) to read nothing, got %q (%v)", str, err)
+	}
+	str, err := remote.ReadString(1316)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str != "hello" {
+		t.Errorf("expected %q, got %q", "hello", str)
+	}
+
+	// In stream mode a short read returns a fragment, the rest follows
+	stream, streamRemote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "0"})
+	defer stream.Close()
+	defer streamRemote.Close()
+	if _, err := stream.WriteString("hello world"); err != nil {
+		t.Fatal(err)
+	}
+	streamRemote.SetReadDeadline(time.Now().Add(time.Second))
+	var got string
+	for len(got) < len("hello world") {
+		str, err := streamRemote.ReadString(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(str) > 5 {
+			t.Fatalf("expected at most 5 bytes, got %q", str)
+		}
+		got += str
+	}
+	if got != "hello world" {
+		t.Errorf("expected %q, got %q", "hello world", got)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "sndbuf": "1048576", "rcvbuf": "1048576"})
+	defer caller.Close()
+	defer remote.Close()
+
+	// Nobody reads on the remote side, so the send buffer eventually fills up
+	// and a write blocks until it is cancelled
+	chunk := make([]byte, 64*1024)
+	cancelled := false
+	for i := 0; i < 1000 && !cancelled; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		timer := time.AfterFunc(200*time.Millisecond, cancel)
+		_, err := caller.WriteContext(ctx, chunk)
+		timer.Stop()
+		cancel()
+		if err == context.Canceled {
+			cancelled = true
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !cancelled {
+		t.Fatal("send buffer never saturated")
+	}
+
+	// The socket must still be usable once the receiver catches up
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			if _, err := remote.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := caller.WriteContext(ctx, chunk); err != nil {
+		t.Errorf("write after cancellation: %v", err)
+	}
+}
+
+func TestWritePartialStream(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "sndbuf": "1048576", "rcvbuf": "1048576"})
+	defer caller.Close()
+	defer remote.Close()
+
+	// Nobody reads yet, so SRT only accepts part of a buffer larger than the
+	// send and receive buffers before the deadline expires
+	data := make([]byte, 8*1024*1024)
+	caller.SetWriteDeadline(time.Now().Add(500 * time.Millisecond))
+	n, err := caller.Write(data)
+	if err == nil || !IsTimeout(err) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if n <= 0 || n >= len(data) {
+		t.Fatalf("expected a partial write, got n=%d", n)
+	}
+
+	// Resuming from b[n:] delivers the whole buffer exactly once
+	received := make(chan int)
+	go func() {
+		total := 0
+		buf := make([]byte, 64*1024)
+		for total < len(data) {
+			m, err := remote.Read(buf)
+			if err != nil {
+				break
+			}
+			total += m
+		}
+		received <- total
+	}()
+	caller.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	m, err := caller.Write(data[n:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n+m != len(data) {
+		t.Errorf("expected %d bytes written in total, got %d", len(data), n+m)
+	}
+	if total := <-received; total != len(data) {
+		t.Errorf("expected %d bytes received, got %d", len(data), total)
+	}
+}
+
+func TestWriteMessageAllOrNothing(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	// A live mode message larger than the payload size is rejected whole
+	n, err := caller.Write(make([]byte, 2000))
+	if err == nil {
+		t.Fatal("expected an error for an oversized message")
+	}
+	if n != 0 {
+		t.Errorf("expected n=0 on a failed message send, got %d", n)
+	}
+}
+
+func TestReadSurvivesSpuriousWakeups(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		n, err := remote.Read(buf)
+		done <- result{n, err}
+	}()
+
+	// Without a deadline the read waits however often it is woken up
+	// without data
+	for i := 0; i < 50; i++ {
+		time.Sleep(2 * time.Millisecond)
+		remote.pd.unblock(ModeRead, false, true)
+	}
+	select {
+	case r := <-done:
+		t.Fatalf("read returned without data: %d, %v", r.n, r.err)
+	default:
+	}
+
+	if _, err := caller.Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-done:
+		if r.err != nil || r.n != 4 {
+			t.Errorf("expected 4 bytes, got %d (%v)", r.n, r.err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("read did not return the data")
+	}
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	const messages = 200
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	received := 0
+	errs := make(chan error, 2)
+	remote.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 1500)
+			for {
+				lock.Lock()
+				done := received == messages
+				lock.Unlock()
+				if done {
+					return
+				}
+				if _, err := remote.Read(buf); err != nil {
+					if errors.Is(err, error(EAsyncRCV)) {
+						errs <- err
+					}
+					return
+				}
+				lock.Lock()
+				received++
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < messages; i++ {
+		if _, err := caller.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Wake the reader still parked once all messages are consumed
+	time.Sleep(100 * time.Millisecond)
+	remote.SetReadDeadline(time.Now())
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("spurious wakeup returned to reader: %v", err)
+	}
+	if received != messages {
+		t.Errorf("expected %d messages, got %d", messages, received)
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	const writers, perWriter = 4, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if _, err := caller.Write([]byte{byte(w), byte(i)}); err != nil {
+					t.Error(err)
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}(w)
+	}
+
+	counts := make([]int, writers)
+	buf := make([]byte, 1500)
+	remote.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < writers*perWriter; i++ {
+		n, err := remote.Read(buf)
+		if err != nil {
+			t.Fatalf("after %d messages: %v", i, err)
+		}
+		if n != 2 {
+			t.Fatalf("unexpected message length %d", n)
+		}
+		counts[buf[0]]++
+	}
+	wg.Wait()
+	for w, c := range counts {
+		if c != perWriter {
+			t.Errorf("writer %d: expected %d messages, got %d", w, perWriter, c)
+		}
+	}
+}
+
+func TestReadChannel(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	packets, errs, cancel := remote.ReadChannel(4)
+	for i := 0; i < 3; i++ {
+		if _, err := caller.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case p := <-packets:
+			if len(p) != 1 || p[0] != byte(i) {
+				t.Errorf("packet %d: unexpected payload %v", i, p)
+			}
+			ReleasePacket(p)
+		case <-time.After(time.Second):
+			t.Fatalf("packet %d not received", i)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-packets:
+		if ok {
+			t.Error("expected the packet channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("packet channel not closed after cancel")
+	}
+	if err, ok := <-errs; ok {
+		t.Errorf("cancel should not report an error, got %v", err)
+	}
+}
+
+func TestSendRecvFile(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file"})
+	defer caller.Close()
+	defer remote.Close()
+
+	dir, err := ioutil.TempDir("", "srtgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	payload := make([]byte, 256*1024)
+	rand.Read(payload)
+	if err := ioutil.WriteFile(src, payload, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		_, err := caller.SendFile(src, 0, int64(len(payload)))
+		sent <- err
+	}()
+	n, err := remote.RecvFile(dst, 0, int64(len(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(payload)) {
+		t.Errorf("expected %d bytes received, got %d", len(payload), n)
+	}
+	got, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("received file differs from the sent one")
+	}
+
+	live, liveRemote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer live.Close()
+	defer liveRemote.Close()
+	if _, err := live.SendFile(src, 0, int64(len(payload))); err == nil {
+		t.Error("expected SendFile to fail in live mode")
+	}
+}
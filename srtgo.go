@@ -13,12 +13,14 @@ static const int get_srt_error_reject_userdefined() { return SRT_REJC_USERDEFINE
 import "C"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"runtime"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
@@ -74,16 +76,31 @@ const (
 )
 
 // SrtSocket - SRT socket
+// A socket can be read from one goroutine while another writes to it.
+// Concurrent reads (or writes) of a non-blocking socket are serialized, so
+// each one completes whole but they are not interleaved.
 type SrtSocket struct {
-	socket      C.int
-	blocking    bool
-	pd          *pollDesc
-	host        string
-	port        uint16
-	options     map[string]string
-	mode        int
-	pktSize     int
-	pollTimeout int64
+	socket C.int
+	// blocking is the receiving mode (SRTO_RCVSYN), which also applies to
+	// connect and accept; writeBlocking is the sending mode (SRTO_SNDSYN).
+	// Both are set by the "blocking" option and changed with SetBlocking.
+	blocking      bool
+	writeBlocking bool
+	pd            *pollDesc
+	host          string
+	port          uint16
+	options       map[string]string
+	mode          int
+	pktSize       int
+	pollTimeout   int64
+	// closed is shared by the copies of a handle, so that only the first
+	// Close releases the socket
+	closed *int32
+	// inListenCallback marks the handle passed to a listen callback, on which
+	// PRE options may still be set although SRT already handles the handshake
+	inListenCallback bool
+	// state is shared by the copies of a handle like closed, see socketState
+	state *socketState
 }
 
 var (
@@ -101,17 +118,68 @@ var (
 
 const defaultPacketSize = 1456
 
+// initCount tracks the number of outstanding InitSRT calls. initMutex guards
+// it together with srt_startup and srt_cleanup, so that no caller is told the
+// library is ready before srt_startup has finished, and startup and cleanup
+// never run concurrently.
+var (
+	initMutex sync.Mutex
+	initCount int
+)
+
 // InitSRT - Initialize srt library
+//
+// Calls are reference-counted: srt_startup runs on the first call only, and the
+// library stays initialized until the matching number of CleanupSRT calls.
+// This lets independent components each pair their own InitSRT/CleanupSRT.
 func InitSRT() {
-	C.srt_startup()
+	InitSRTErr()
+}
+
+// InitSRTErr - Initialize srt library, returning an error if srt_startup failed
+// Reference-counted like InitSRT; a failed call does not count.
+func InitSRTErr() error {
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	if initCount == 0 && C.srt_startup() == SRT_ERROR {
+		return fmt.Errorf("Error in srt_startup: %w", srtGetAndClearErrorThreadSafe())
+	}
+	initCount++
+	return nil
 }
 
 // CleanupSRT - Cleanup SRT lib
+//
+// srt_cleanup only runs when the last outstanding InitSRT is released.
+// Calls without a matching InitSRT are ignored.
 func CleanupSRT() {
-	C.srt_cleanup()
+	CleanupSRTErr()
+}
+
+// CleanupSRTErr - Cleanup SRT lib, returning an error if srt_cleanup failed
+// Reference-counted like CleanupSRT.
+func CleanupSRTErr() error {
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	if initCount == 0 {
+		return nil
+	}
+	initCount--
+	if initCount > 0 {
+		return nil
+	}
+	if C.srt_cleanup() == SRT_ERROR {
+		return fmt.Errorf("Error in srt_cleanup: %w", srtGetAndClearErrorThreadSafe())
+	}
+	return nil
 }
 
 // NewSrtSocket - Create a new SRT Socket
+// Sockets created with the "blocking" option are never registered with the
+// internal epoll poller; only non-blocking sockets use it to park Read/Write.
+// After DisablePollServer sockets are blocking by default.
+// A listener created with port 0 binds an ephemeral port chosen by the OS,
+// which BoundPort reports once Listen has been called.
 func NewSrtSocket(host string, port uint16, options map[string]string) *SrtSocket {
 	s := new(SrtSocket)
 
@@ -124,6 +192,8 @@ func NewSrtSocket(host string, port uint16, options map[string]string) *SrtSocke
 	s.port = port
 	s.options = options
 	s.pollTimeout = -1
+	s.closed = new(int32)
+	s.state = newSocketState()
 
 	val, exists := options["pktsize"]
 	if exists {
@@ -137,12 +207,18 @@ func NewSrtSocket(host string, port uint16, options map[string]string) *SrtSocke
 	}
 
 	val, exists = options["blocking"]
-	if exists && val != "0" {
+	if exists && val != "0" || !exists && pollServerDisabled() {
 		s.blocking = true
+		s.writeBlocking = true
+	} else if pollServerDisabled() {
+		// Non-blocking mode requires the poll server, see DisablePollServer
+		C.srt_close(s.socket)
+		return nil
 	}
 
+	// Blocking sockets never wait on the poller, so skip epoll registration
 	if !s.blocking {
-		s.pd = pollDescInit(s.socket)
+		s.pd = pollDescInit(s.socket, s.state)
 	}
 
 	finalizer := func(obj interface{}) {
@@ -165,20 +241,23 @@ func NewSrtSocket(host string, port uint16, options map[string]string) *SrtSocke
 	return s
 }
 
-func newFromSocket(acceptSocket *SrtSocket, socket C.SRTSOCKET) (*SrtSocket, error) {
+func newFromSocket(acceptSocket *SrtSocket, socket C.SRTSOCKET, state *socketState) (*SrtSocket, error) {
 	s := new(SrtSocket)
 	s.socket = socket
 	s.pktSize = acceptSocket.pktSize
 	s.blocking = acceptSocket.blocking
+	s.writeBlocking = acceptSocket.writeBlocking
 	s.pollTimeout = acceptSocket.pollTimeout
+	s.closed = new(int32)
+	s.state = state
 
 	err := acceptSocket.postconfiguration(s)
 	if err != nil {
 		return nil, err
 	}
 
-	if !s.blocking {
-		s.pd = pollDescInit(s.socket)
+	if !s.blocking || !s.writeBlocking {
+		s.pd = pollDescInit(s.socket, s.state)
 	}
 
 	finalizer := func(obj interface{}) {
@@ -202,6 +281,10 @@ func (s SrtSocket) GetSocket() C.int {
 // Listen for incoming connections. The backlog setting defines how many sockets
 // may be allowed to wait until they are accepted (excessive connection requests
 // are rejected in advance)
+//
+// When listening on the IPv6 wildcard address "::" without an explicit
+// "ipv6only" option, ipv6only is set to 0 so that the listener accepts both
+// IPv6 and IPv4-mapped callers.
 func (s *SrtSocket) Listen(backlog int) error {
 	nbacklog := C.int(backlog)
 
@@ -210,16 +293,27 @@ func (s *SrtSocket) Listen(backlog int) error {
 		return err
 	}
 
+	if _, ok := s.options["ipv6only"]; !ok {
+		if ip := net.ParseIP(s.host); ip != nil && ip.To4() == nil && ip.IsUnspecified() {
+			if err := s.SetSockOptInt(SRTO_IPV6ONLY, 0); err != nil {
+				return err
+			}
+		}
+	}
+
 	res := C.srt_bind(s.socket, sa, C.int(salen))
 	if res == SRT_ERROR {
-		C.srt_close(s.socket)
-		return fmt.Errorf("Error in srt_bind: %w", srtGetAndClearErrorThreadSafe())
+		err := fmt.Errorf("Error in srt_bind: %w", srtGetAndClearErrorThreadSafe())
+		s.Close()
+		return err
 	}
 
+	s.state.trackListenBacklog()
 	res = C.srt_listen(s.socket, nbacklog)
 	if res == SRT_ERROR {
-		C.srt_close(s.socket)
-		return fmt.Errorf("Error in srt_listen: %w", srtGetAndClearErrorThreadSafe())
+		err := fmt.Errorf("Error in srt_listen: %w", srtGetAndClearErrorThreadSafe())
+		s.Close()
+		return err
 	}
 
 	err = s.postconfiguration(s)
@@ -232,35 +326,153 @@ func (s *SrtSocket) Listen(backlog int) error {
 
 // Connect to a remote endpoint
 func (s *SrtSocket) Connect() error {
+	if s.port == 0 {
+		return fmt.Errorf("cannot connect to port 0")
+	}
+	start := time.Now()
 	sa, salen, err := CreateAddrInet(s.host, s.port)
 	if err != nil {
 		return err
 	}
+	return s.connect(context.Background(), start, nil, sa, salen)
+}
 
-	res := C.srt_connect(s.socket, sa, C.int(salen))
+// ConnectBind binds the socket to localHost:localPort and connects it to
+// remoteHost:remotePort in one step with srt_connect_bind, e.g. to send from a
+// given interface of a multihomed host or to use a fixed local port, which
+// rendezvous mode requires. Port 0 lets the OS choose the local port. Both
+// addresses must resolve to the same address family. Returns the local port
+// actually used. The socket is closed if the connection fails, including a
+// handshake that fails or times out on a non-blocking socket; it stays usable
+// if an address cannot be resolved or the families differ.
+func (s *SrtSocket) ConnectBind(localHost string, localPort uint16, remoteHost string, remotePort uint16) (uint16, error) {
+	if remotePort == 0 {
+		return 0, fmt.Errorf("cannot connect to port 0")
+	}
+	start := time.Now()
+	local, locallen, err := CreateAddrInet(localHost, localPort)
+	if err != nil {
+		return 0, fmt.Errorf("local address: %w", err)
+	}
+	sa, salen, err := CreateAddrInet(remoteHost, remotePort)
+	if err != nil {
+		return 0, fmt.Errorf("remote address: %w", err)
+	}
+	if locallen != salen {
+		return 0, fmt.Errorf("local address %s and remote address %s are of different address families", localHost, remoteHost)
+	}
+	if err := s.connect(context.Background(), start, local, sa, salen); err != nil {
+		return 0, err
+	}
+	return s.BoundPort()
+}
+
+// ConnectDeadline connects like Connect, but bounds the whole operation by
+// deadline: the resolution of the host name, the implicit bind and the SRT
+// handshake. The conntimeo option (see ConnectTimeout) only covers the
+// handshake, so a slow name lookup can make Connect take much longer than it.
+// ConnectDeadline overrides conntimeo with the time left after resolution.
+// Errors caused by the deadline match context.DeadlineExceeded or, for the
+// handshake, report Timeout() true.
+func (s *SrtSocket) ConnectDeadline(deadline time.Time) error {
+	if s.port == 0 {
+		return fmt.Errorf("cannot connect to port 0")
+	}
+	start := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	sa, salen, err := createAddrInetContext(ctx, s.host, s.port)
+	if err != nil {
+		return err
+	}
+	remaining := time.Until(deadline)
+	if remaining < MinConnectTimeout {
+		return fmt.Errorf("connect deadline reached after address resolution: %w", context.DeadlineExceeded)
+	}
+	if err := s.ConnectTimeout(remaining); err != nil {
+		return err
+	}
+	return s.connect(ctx, start, nil, sa, salen)
+}
+
+// connect performs the SRT handshake; start is when the caller began
+// connecting and is the reference of ConnectDuration. A non-nil local address,
+// of the same family as sa, is bound atomically with the connect.
+func (s *SrtSocket) connect(ctx context.Context, start time.Time, local, sa *C.struct_sockaddr, salen int) error {
+	var res C.int
+	if local != nil {
+		res = C.srt_connect_bind(s.socket, local, sa, C.int(salen))
+	} else {
+		res = C.srt_connect(s.socket, sa, C.int(salen))
+	}
+	// A failed connection is closed through Close, so that the poll
+	// descriptor is released and a later Close does not close it again
 	if res == SRT_ERROR {
-		C.srt_close(s.socket)
-		return srtGetAndClearErrorThreadSafe()
+		err := s.classifyRejection(srtGetAndClearErrorThreadSafe())
+		s.Close()
+		return err
 	}
 
 	if !s.blocking {
-		if err := s.pd.wait(ModeWrite); err != nil {
+		if err := s.pd.waitContext(ctx, ModeWrite); err != nil {
+			err = s.classifyRejection(err)
+			s.Close()
 			return err
 		}
 	}
 
-	err = s.postconfiguration(s)
+	err := s.postconfiguration(s)
 	if err != nil {
+		s.Close()
 		return fmt.Errorf("Error setting post socket options in connect")
 	}
 
+	s.state.setConnectDuration(time.Since(start))
 	return nil
 }
 
+// Dial creates a socket with the given options and connects it to host:port.
+// A positive timeout overrides the conntimeo option for this attempt only, so
+// callers retrying a connection can pass a different timeout every time. The
+// socket is closed if the connection fails.
+func Dial(host string, port uint16, options map[string]string, timeout time.Duration) (*SrtSocket, error) {
+	s := NewSrtSocket(host, port, options)
+	if s == nil {
+		return nil, fmt.Errorf("failed to create socket")
+	}
+	if timeout > 0 {
+		if err := s.ConnectTimeout(timeout); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	if err := s.Connect(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
 // Stats - Retrieve stats from the SRT socket
 func (s SrtSocket) Stats() (*SrtStats, error) {
+	return s.stats(true)
+}
+
+// StatsNoClear - Retrieve stats from the SRT socket without resetting the
+// interval ("Local measurements") counters, so that several observers can
+// read them without interfering with each other
+func (s SrtSocket) StatsNoClear() (*SrtStats, error) {
+	return s.stats(false)
+}
+
+// stats retrieves stats from the SRT socket, resetting the interval counters
+// only if clear is set
+func (s SrtSocket) stats(clear bool) (*SrtStats, error) {
 	var stats C.SRT_TRACEBSTATS = C.SRT_TRACEBSTATS{}
-	var b C.int = 1
+	var b C.int = 0
+	if clear {
+		b = 1
+	}
 	if C.srt_bstats(s.socket, &stats, b) == SRT_ERROR {
 		return nil, fmt.Errorf("Error getting stats, %w", srtGetAndClearErrorThreadSafe())
 	}
@@ -302,52 +514,223 @@ func (s *SrtSocket) SetWriteDeadline(deadline time.Time) {
 	s.pd.setDeadline(deadline, ModeWrite)
 }
 
+// ReadDeadline returns the deadline set with SetReadDeadline or SetDeadline,
+// or the zero time if none is set, e.g. to restore it after an operation that
+// needs its own deadline. Safe to call concurrently with the setters.
+func (s SrtSocket) ReadDeadline() time.Time {
+	if s.pd == nil {
+		return time.Time{}
+	}
+	return s.pd.deadline(ModeRead)
+}
+
+// WriteDeadline returns the deadline set with SetWriteDeadline or
+// SetDeadline, or the zero time if none is set
+func (s SrtSocket) WriteDeadline() time.Time {
+	if s.pd == nil {
+		return time.Time{}
+	}
+	return s.pd.deadline(ModeWrite)
+}
+
 // Socket returns the underlying C socket for advanced operations
 func (s *SrtSocket) Socket() C.int {
 	return s.socket
 }
 
-// Close the SRT socket
-func (s *SrtSocket) Close() {
+// isClosed reports whether Close has been called on the socket, through any
+// copy of its handle
+func (s SrtSocket) isClosed() bool {
+	return s.socket == SRT_INVALID_SOCK || s.closed != nil && atomic.LoadInt32(s.closed) != 0
+}
+
+// Close the SRT socket. Close is idempotent and implements io.Closer: only the
+// first call, on this handle or on a copy of it, closes the socket and
+// releases its resources; later calls return nil without touching the socket
+// or the poll server. It always returns nil.
+func (s *SrtSocket) Close() error {
+	if s.socket == SRT_INVALID_SOCK || s.closed != nil && !atomic.CompareAndSwapInt32(s.closed, 0, 1) {
+		s.socket = SRT_INVALID_SOCK
+		return nil
+	}
 
-	C.srt_close(s.socket)
+	socket := s.socket
+	C.srt_close(socket)
 	s.socket = SRT_INVALID_SOCK
-	if !s.blocking {
+	if s.pd != nil {
 		s.pd.close()
 	}
+	if s.state != nil {
+		s.state.release()
+	}
 	callbackMutex.Lock()
-	if ptr, exists := listenCallbackMap[s.socket]; exists {
+	if ptr, exists := listenCallbackMap[socket]; exists {
 		gopointer.Unref(ptr)
+		delete(listenCallbackMap, socket)
 	}
-	if ptr, exists := connectCallbackMap[s.socket]; exists {
+	if ptr, exists := connectCallbackMap[socket]; exists {
 		gopointer.Unref(ptr)
+		delete(connectCallbackMap, socket)
 	}
 	callbackMutex.Unlock()
+	return nil
+}
+
+// SetUserData attaches an application value to the socket. It can be
+// retrieved with UserData from any copy of the handle, including the one
+// passed to a connect callback; a value set on the handle passed to a listen
+// callback is kept by the socket Accept returns for that connection. The value
+// is released on Close.
+func (s SrtSocket) SetUserData(v interface{}) {
+	s.state.mu.Lock()
+	s.state.userData = v
+	s.state.mu.Unlock()
+}
+
+// UserData returns the value attached with SetUserData, or nil
+func (s SrtSocket) UserData() interface{} {
+	if s.state == nil {
+		return nil
+	}
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	return s.state.userData
+}
+
+// closeDrainInterval is the polling period used by CloseWithTimeout
+const closeDrainInterval = 10 * time.Millisecond
+
+// CloseWithTimeout waits until the send buffer has been fully delivered, or
+// until the timeout expires, and then closes the socket. This avoids truncating
+// the tail of a transfer, which matters most in file mode. If the timeout
+// expires the socket is still closed and an error reporting the number of
+// undelivered bytes is returned.
+func (s *SrtSocket) CloseWithTimeout(timeout time.Duration) error {
+	if !s.drainSendBuffer(time.Now().Add(timeout)) {
+		var blocks, bytes C.size_t
+		C.srt_getsndbuffer(s.socket, &blocks, &bytes)
+		s.Close()
+		return fmt.Errorf("close timed out after %s with %d bytes undelivered", timeout, int(bytes))
+	}
+	s.Close()
+	return nil
+}
+
+// drainSendBuffer waits until the send buffer is empty or the connection is
+// no longer established. A zero deadline waits without limit. Returns false
+// if the deadline expired with data still pending.
+func (s SrtSocket) drainSendBuffer(deadline time.Time) bool {
+	for {
+		pending, err := s.GetSockOptInt(SRTO_SNDDATA)
+		if err != nil || pending == 0 {
+			return true
+		}
+		if C.srt_getsockstate(s.socket) != C.SRTS_CONNECTED {
+			return true
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(closeDrainInterval)
+	}
+}
+
+// CloseWrite shuts down the sending side of the socket while keeping the
+// receiving side open, similar to TCP half-close. SRT has no native
+// half-close, so this is done at the Go layer: CloseWrite makes every further
+// Write, WriteV, WriteMessage, ReadFrom and SendFile fail with SrtWriteClosed,
+// then waits until all data already written has been acknowledged by the peer
+// (or the connection breaks). The wait is bounded by the write deadline, or
+// without one by the linger time of the socket (0 by default in live mode);
+// if it ends with data left in the send buffer an error reporting the
+// undelivered bytes is returned, and the sending side is closed all the same.
+// Read keeps working until the peer closes the connection. The peer is not
+// notified at the protocol level; applications that need an end-of-stream
+// signal must send their own marker before calling CloseWrite. The socket
+// must still be closed with Close.
+func (s SrtSocket) CloseWrite() error {
+	if s.socket == SRT_INVALID_SOCK {
+		return &SrtSocketClosed{}
+	}
+	s.state.mu.Lock()
+	s.state.writeClosed = true
+	s.state.mu.Unlock()
+
+	deadline := s.WriteDeadline()
+	if deadline.IsZero() {
+		linger, err := getSocketLingerOption(&s)
+		if err != nil {
+			return err
+		}
+		deadline = time.Now().Add(time.Duration(linger) * time.Second)
+	}
+	if !s.drainSendBuffer(deadline) {
+		var blocks, bytes C.size_t
+		C.srt_getsndbuffer(s.socket, &blocks, &bytes)
+		return fmt.Errorf("CloseWrite timed out with %d bytes undelivered", int(bytes))
+	}
+	return nil
+}
+
+// checkWriteClosed returns SrtWriteClosed if CloseWrite was called on the socket
+func (s SrtSocket) checkWriteClosed() error {
+	if s.state == nil {
+		return nil
+	}
+	s.state.mu.Lock()
+	closed := s.state.writeClosed
+	s.state.mu.Unlock()
+	if closed {
+		return &SrtWriteClosed{}
+	}
+	return nil
 }
 
 // ListenCallbackFunc specifies a function to be called before a connecting socket is passed to accept
 type ListenCallbackFunc func(socket *SrtSocket, version int, addr *net.UDPAddr, streamid string) bool
 
+// listenCallbackEntry is the opaque value passed to srtListenCBWrapper; SRT
+// only passes the accepted socket, so the listener is recorded alongside
+type listenCallbackEntry struct {
+	state *socketState
+	cb    ListenCallbackFunc
+}
+
 //export srtListenCBWrapper
 func srtListenCBWrapper(arg unsafe.Pointer, socket C.SRTSOCKET, hsVersion C.int, peeraddr *C.struct_sockaddr, streamid *C.char) C.int {
-	userCB := gopointer.Restore(arg).(ListenCallbackFunc)
+	entry := gopointer.Restore(arg).(listenCallbackEntry)
 
-	// Reuse socket struct to reduce allocations
-	s := &SrtSocket{socket: socket}
-	udpAddr, _ := udpAddrFromSockaddr((*syscall.RawSockaddrAny)(unsafe.Pointer(peeraddr)))
+	// The state of the connection is handed to the socket returned by Accept
+	pending := entry.state.admitPending(socket)
+	if entry.cb != nil {
+		s := &SrtSocket{socket: socket, inListenCallback: true, state: pending}
+		udpAddr, _ := udpAddrFromSockaddr((*syscall.RawSockaddrAny)(unsafe.Pointer(peeraddr)))
 
-	if userCB(s, int(hsVersion), udpAddr, C.GoString(streamid)) {
-		return 0
+		if !entry.cb(s, int(hsVersion), udpAddr, C.GoString(streamid)) {
+			entry.state.dropPending(socket)
+			return SRT_ERROR
+		}
 	}
-	return SRT_ERROR
+	return 0
 }
 
 // SetListenCallback - set a function to be called early in the handshake before a client
 // is handed to accept on a listening socket.
 // The connection can be rejected by returning false from the callback.
 // See examples/echo-receiver for more details.
+//
+// The socket passed to the callback already carries the options of the
+// listener, and SRT builds its handshake response only after the callback
+// returns, so options can be set on it per connection, e.g. a per-tenant
+// passphrase chosen by streamid. PRE options negotiated in the handshake take
+// effect this way: passphrase (SetPassphrase), pbkeylen, enforcedencryption,
+// latency, rcvlatency, peerlatency and fc.
+// PREBIND options cannot be set, as the socket shares the UDP port of the
+// listener, and options that must match the listener's transmission type
+// (transtype, messageapi, payloadsize, congestion) should not be changed.
+// The socket handle is only valid for the duration of the callback.
 func (s SrtSocket) SetListenCallback(cb ListenCallbackFunc) error {
-	ptr := gopointer.Save(cb)
+	ptr := gopointer.Save(listenCallbackEntry{state: s.state, cb: cb})
 	result := C.srt_listen_callback(s.socket, (*C.srt_listen_callback_fn)(C.srtListenCB), ptr)
 
 	if result == SRT_ERROR {
@@ -365,23 +748,46 @@ func (s SrtSocket) SetListenCallback(cb ListenCallbackFunc) error {
 }
 
 // ConnectCallbackFunc specifies a function to be called after a socket or connection in a group has failed.
+// err is nil if SRT reports success, otherwise it holds the SRTErrno of the failure.
+// token identifies the group member link the notification refers to, or -1.
 type ConnectCallbackFunc func(socket *SrtSocket, err error, addr *net.UDPAddr, token int)
 
+// connectCallbackEntry is the opaque value passed to srtConnectCBWrapper
+type connectCallbackEntry struct {
+	socket C.int
+	state  *socketState
+	cb     ConnectCallbackFunc
+}
+
 //export srtConnectCBWrapper
 func srtConnectCBWrapper(arg unsafe.Pointer, socket C.SRTSOCKET, errcode C.int, peeraddr *C.struct_sockaddr, token C.int) {
-	userCB := gopointer.Restore(arg).(ConnectCallbackFunc)
+	entry := gopointer.Restore(arg).(connectCallbackEntry)
 
-	// Reuse socket struct to reduce allocations
-	s := &SrtSocket{socket: socket}
-	udpAddr, _ := udpAddrFromSockaddr((*syscall.RawSockaddrAny)(unsafe.Pointer(peeraddr)))
+	// The handle shares the state of the socket the callback was set on,
+	// group members get their own
+	state := entry.state
+	if socket != entry.socket {
+		state = newSocketState()
+	}
+	s := &SrtSocket{socket: socket, state: state}
+	var udpAddr *net.UDPAddr
+	if peeraddr != nil {
+		udpAddr, _ = udpAddrFromSockaddr((*syscall.RawSockaddrAny)(unsafe.Pointer(peeraddr)))
+	}
 
-	userCB(s, SRTErrno(errcode), udpAddr, int(token))
+	var err error
+	if SRTErrno(errcode) != Success {
+		err = SRTErrno(errcode)
+	}
+	entry.cb(s, err, udpAddr, int(token))
 }
 
 // SetConnectCallback - set a function to be called after a socket or connection in a group has failed
 // Note that the function is not guaranteed to be called if the socket is set to blocking mode.
+// The callback is invoked from an SRT thread, so it must not block; the reference to it is
+// released when the socket is closed.
 func (s SrtSocket) SetConnectCallback(cb ConnectCallbackFunc) error {
-	ptr := gopointer.Save(cb)
+	ptr := gopointer.Save(connectCallbackEntry{socket: s.socket, state: s.state, cb: cb})
 	result := C.srt_connect_callback(s.socket, (*C.srt_connect_callback_fn)(C.srtConnectCB), ptr)
 
 	if result == SRT_ERROR {
@@ -437,6 +843,39 @@ func (s SrtSocket) SetRejectReason(value int) error {
 	return nil
 }
 
+// RejectReason - return the reason why the connection was rejected, one of the SRT_REJ_*
+// values or a RejectionReason* value set by the peer with SetRejectReason
+func (s SrtSocket) RejectReason() int {
+	return int(C.srt_getrejectreason(s.socket))
+}
+
+// classifyRejection turns a failed connection caused by an encryption
+// mismatch into an *EncryptionError, other errors are returned unchanged
+func (s SrtSocket) classifyRejection(err error) error {
+	reason := C.srt_getrejectreason(s.socket)
+	if reason != C.SRT_REJ_UNSECURE && reason != C.SRT_REJ_BADSECRET {
+		return err
+	}
+
+	kmState := SrtKmStateUnsecured
+	if v, kmErr := s.GetSockOptInt(SRTO_SNDKMSTATE); kmErr == nil {
+		kmState = SrtKmState(v)
+	}
+
+	encErr := &EncryptionError{Reason: int(reason), KmState: kmState, Err: err}
+	switch {
+	case reason == C.SRT_REJ_BADSECRET:
+		encErr.Fault = EncryptionFaultMismatch
+	case kmState == SrtKmStateUnsecured || kmState == SrtKmStateNoSecret:
+		// No local secret, the peer requires one
+		encErr.Fault = EncryptionFaultLocal
+	default:
+		// Local secret configured, the peer has none
+		encErr.Fault = EncryptionFaultPeer
+	}
+	return encErr
+}
+
 // GetSockOptByte - return byte value obtained with srt_getsockopt
 func (s SrtSocket) GetSockOptByte(opt int) (byte, error) {
 	var v byte
@@ -479,7 +918,8 @@ func (s SrtSocket) GetSockOptInt64(opt int) (int64, error) {
 
 // GetSockOptString - return string value obtained with srt_getsockopt
 func (s SrtSocket) GetSockOptString(opt int) (string, error) {
-	buf := make([]byte, 256)
+	// Large enough for the longest string option, a streamid
+	buf := make([]byte, maxStreamIDLen)
 	l := len(buf)
 
 	err := s.getSockOpt(opt, unsafe.Pointer(&buf[0]), &l)
@@ -627,6 +1067,12 @@ func (s SrtSocket) preconfiguration() (int, error) {
 		mode = ModeFailure
 	}
 
+	// Apply PREBIND options first (must be set before bind/connect)
+	if err := s.applyPrebindOptions(); err != nil {
+		return ModeFailure, fmt.Errorf("Error setting PREBIND options: %w", err)
+	}
+
+	// After the PREBIND options, as transtype resets linger
 	if linger, ok := s.options["linger"]; ok {
 		li, err := strconv.Atoi(linger)
 		if err == nil {
@@ -638,11 +1084,6 @@ func (s SrtSocket) preconfiguration() (int, error) {
 		}
 	}
 
-	// Apply PREBIND options first (must be set before bind/connect)
-	if err := s.applyPrebindOptions(); err != nil {
-		return ModeFailure, fmt.Errorf("Error setting PREBIND options: %w", err)
-	}
-
 	// Apply PRE options (must be set before listen/connect)
 	if err := s.applyPreOptions(); err != nil {
 		return ModeFailure, fmt.Errorf("Error setting PRE options: %w", err)
@@ -652,23 +1093,64 @@ func (s SrtSocket) preconfiguration() (int, error) {
 }
 
 func (s SrtSocket) postconfiguration(sck *SrtSocket) error {
-	var blocking C.int
-	if s.blocking {
-		blocking = 1
-	} else {
-		blocking = 0
+	if err := setSyncOption(sck.socket, C.SRTO_SNDSYN, s.writeBlocking); err != nil {
+		return fmt.Errorf("Error in postconfiguration setting SRTO_SNDSYN: %w", err)
 	}
-
-	res := C.srt_setsockopt(sck.socket, 0, C.SRTO_SNDSYN, unsafe.Pointer(&blocking), C.int(unsafe.Sizeof(blocking)))
-	if res == -1 {
-		return fmt.Errorf("Error in postconfiguration setting SRTO_SNDSYN: %w", srtGetAndClearErrorThreadSafe())
-	}
-
-	res = C.srt_setsockopt(sck.socket, 0, C.SRTO_RCVSYN, unsafe.Pointer(&blocking), C.int(unsafe.Sizeof(blocking)))
-	if res == -1 {
-		return fmt.Errorf("Error in postconfiguration setting SRTO_RCVSYN: %w", srtGetAndClearErrorThreadSafe())
+	if err := setSyncOption(sck.socket, C.SRTO_RCVSYN, s.blocking); err != nil {
+		return fmt.Errorf("Error in postconfiguration setting SRTO_RCVSYN: %w", err)
 	}
 
 	// Apply POST options (can be set anytime, including after connection)
 	return sck.applyPostOptions()
 }
+
+// setSyncOption sets SRTO_RCVSYN or SRTO_SNDSYN
+func setSyncOption(socket C.SRTSOCKET, opt C.SRT_SOCKOPT, blocking bool) error {
+	var val C.int
+	if blocking {
+		val = 1
+	}
+	if C.srt_setsockopt(socket, 0, opt, unsafe.Pointer(&val), C.int(unsafe.Sizeof(val))) == -1 {
+		return srtGetAndClearErrorThreadSafe()
+	}
+	return nil
+}
+
+// SetBlocking switches the receiving side (SRTO_RCVSYN, which also governs
+// Connect and Accept) and the sending side (SRTO_SNDSYN) of the socket
+// between blocking and non-blocking mode, e.g. to connect in blocking mode
+// and then read with deadlines. The socket is registered with the internal
+// poller while either side is non-blocking and removed from it once both
+// block; the read and write deadlines are reset then.
+//
+// SetBlocking changes the mode of this handle only: copies of the SrtSocket
+// made before keep the old mode and must not be used afterwards. It must not
+// be called while other goroutines read from or write to the socket.
+func (s *SrtSocket) SetBlocking(read, write bool) error {
+	if s.socket == SRT_INVALID_SOCK {
+		return &SrtSocketClosed{}
+	}
+	if (!read || !write) && pollServerDisabled() {
+		return fmt.Errorf("non-blocking mode is not available after DisablePollServer")
+	}
+	if err := setSyncOption(s.socket, C.SRTO_RCVSYN, read); err != nil {
+		return fmt.Errorf("could not set SRTO_RCVSYN: %w", err)
+	}
+	if err := setSyncOption(s.socket, C.SRTO_SNDSYN, write); err != nil {
+		return fmt.Errorf("could not set SRTO_SNDSYN: %w", err)
+	}
+	s.blocking = read
+	s.writeBlocking = write
+
+	polled := !read || !write
+	if polled && s.pd == nil {
+		// SRT reports the current readiness when the socket is added, so no
+		// edge is lost for data that arrived in blocking mode
+		s.pd = pollDescInit(s.socket, s.state)
+	} else if !polled && s.pd != nil {
+		// Not returned to the pool: copies of the handle may still refer to it
+		s.pd.close()
+		s.pd = nil
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package srtgo
 
 import (
+	"context"
+	"errors"
 	"math/rand"
+	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -185,6 +189,179 @@ func TestAcceptBlocking(t *testing.T) {
 	AcceptHelper(1, 8092, options, t)
 }
 
+func TestAcceptTimeout(t *testing.T) {
+	InitSRT()
+
+	for _, blocking := range []string{"0", "1"} {
+		port := randomPort()
+		listener := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": blocking, "mode": "listener"})
+		if listener == nil {
+			t.Fatal("Could not create a srt socket")
+		}
+		if err := listener.Listen(1); err != nil {
+			t.Fatal(err)
+		}
+
+		p := pollServerCtx()
+		p.pollDescLock.Lock()
+		registered := len(p.pollDescs)
+		p.pollDescLock.Unlock()
+
+		for i := 0; i < 5; i++ {
+			start := time.Now()
+			_, _, err := listener.AcceptTimeout(50 * time.Millisecond)
+			var timeout interface{ Timeout() bool }
+			if !errors.As(err, &timeout) || !timeout.Timeout() {
+				t.Fatalf("blocking=%s: expected a timeout error, got %v", blocking, err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("blocking=%s: accept timed out after %v", blocking, elapsed)
+			}
+		}
+
+		p.pollDescLock.Lock()
+		if n := len(p.pollDescs); n != registered {
+			t.Errorf("blocking=%s: %d poll registrations after timed out accepts, expected %d", blocking, n, registered)
+		}
+		p.pollDescLock.Unlock()
+
+		go func() {
+			caller := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": "1", "mode": "caller"})
+			if caller != nil {
+				caller.Connect()
+				time.Sleep(500 * time.Millisecond)
+				caller.Close()
+			}
+		}()
+		accepted, _, err := listener.AcceptTimeout(2 * time.Second)
+		if err != nil {
+			t.Fatalf("blocking=%s: %v", blocking, err)
+		}
+		accepted.Close()
+		listener.Close()
+	}
+}
+
+func TestAcceptTimeoutBlockingConcurrentAccept(t *testing.T) {
+	InitSRT()
+
+	listener := NewSrtSocket("127.0.0.1", 0, map[string]string{"blocking": "1", "mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		socket, _, err := listener.Accept()
+		if err == nil {
+			socket.Close()
+		}
+		accepted <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// The concurrent Accept takes the only connection, AcceptTimeout must
+	// still return once its timeout expires
+	const timeout = 300 * time.Millisecond
+	start := time.Now()
+	timedOut := make(chan error, 1)
+	go func() {
+		_, _, err := listener.AcceptTimeout(timeout)
+		timedOut <- err
+	}()
+	caller := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": "1", "mode": "caller"})
+	if caller == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer caller.Close()
+	if err := caller.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-accepted; err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-timedOut:
+		var terr interface{ Timeout() bool }
+		if !errors.As(err, &terr) || !terr.Timeout() {
+			t.Errorf("expected a timeout error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > timeout+200*time.Millisecond {
+			t.Errorf("AcceptTimeout returned after %v, timeout was %v", elapsed, timeout)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("AcceptTimeout blocked beyond its timeout")
+	}
+}
+
+func TestAcceptTimeoutConcurrentAccept(t *testing.T) {
+	InitSRT()
+
+	port := randomPort()
+	listener := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": "0", "mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+
+	timedOut := make(chan error, 1)
+	go func() {
+		_, _, err := listener.AcceptTimeout(100 * time.Millisecond)
+		timedOut <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	accepted := make(chan error, 1)
+	go func() {
+		socket, _, err := listener.Accept()
+		if err == nil {
+			socket.Close()
+		}
+		accepted <- err
+	}()
+
+	// The timeout of AcceptTimeout must not end the concurrent Accept
+	if err := <-timedOut; err == nil {
+		t.Fatal("expected AcceptTimeout to time out")
+	}
+	if d := listener.ReadDeadline(); !d.IsZero() {
+		t.Errorf("AcceptTimeout changed the read deadline to %v", d)
+	}
+	select {
+	case err := <-accepted:
+		t.Fatalf("Accept returned before a connection arrived: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	caller := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": "1", "mode": "caller"})
+	if caller == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer caller.Close()
+	if err := caller.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-accepted:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Accept did not return the connection")
+	}
+}
+
 func TestMultipleAcceptNonBlocking(t *testing.T) {
 	InitSRT()
 
@@ -261,3 +438,1174 @@ func TestSetSockOptBool(t *testing.T) {
 		t.Errorf("Failed to set SRTO_MESSAGEAPI expected %t, got %t\n", expected, v)
 	}
 }
+
+func dualStackHelper(t *testing.T, ipv6only string, caller string) error {
+	port := randomPort()
+	options := map[string]string{"blocking": "1", "transtype": "file", "mode": "listener", "ipv6only": ipv6only}
+	listener := NewSrtSocket("::", port, options)
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+
+	go func() {
+		sock, _, err := listener.Accept()
+		if err == nil {
+			sock.Close()
+		}
+	}()
+
+	s := NewSrtSocket(caller, port, map[string]string{"blocking": "1", "transtype": "file", "mode": "caller", "conntimeo": "1000"})
+	if s == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer s.Close()
+	return s.Connect()
+}
+
+func TestListenDualStack(t *testing.T) {
+	InitSRT()
+
+	if err := dualStackHelper(t, "0", "::1"); err != nil {
+		t.Errorf("IPv6 caller rejected with ipv6only=0: %v", err)
+	}
+	if err := dualStackHelper(t, "0", "127.0.0.1"); err != nil {
+		t.Errorf("IPv4 caller rejected with ipv6only=0: %v", err)
+	}
+	if err := dualStackHelper(t, "1", "::1"); err != nil {
+		t.Errorf("IPv6 caller rejected with ipv6only=1: %v", err)
+	}
+	if err := dualStackHelper(t, "1", "127.0.0.1"); err == nil {
+		t.Error("IPv4 caller accepted with ipv6only=1")
+	}
+}
+
+func currentInitCount() int {
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	return initCount
+}
+
+func TestInitSRTRefCount(t *testing.T) {
+	// Hold a reference so the nested calls below never tear down the library
+	InitSRT()
+	defer CleanupSRT()
+	base := currentInitCount()
+
+	InitSRT()
+	InitSRT()
+	if n := currentInitCount(); n != base+2 {
+		t.Errorf("expected init count %d, got %d", base+2, n)
+	}
+
+	CleanupSRT()
+	if n := currentInitCount(); n != base+1 {
+		t.Errorf("expected init count %d, got %d", base+1, n)
+	}
+
+	// Library must still be usable after a nested cleanup
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Error("Could not create a srt socket after nested cleanup")
+	} else {
+		a.Close()
+	}
+
+	CleanupSRT()
+	if n := currentInitCount(); n != base {
+		t.Errorf("expected init count %d, got %d", base, n)
+	}
+}
+
+func TestInitSRTConcurrent(t *testing.T) {
+	InitSRT()
+	defer CleanupSRT()
+	base := currentInitCount()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if err := InitSRTErr(); err != nil {
+					t.Error(err)
+					return
+				}
+				CleanupSRT()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := currentInitCount(); n != base {
+		t.Errorf("expected init count %d, got %d", base, n)
+	}
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Fatal("Could not create a srt socket after concurrent init and cleanup")
+	}
+	a.Close()
+}
+
+func TestUserData(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+
+	if a.UserData() != nil {
+		t.Error("expected no user data on a new socket")
+	}
+	a.SetUserData("stream-1")
+
+	// Copies of the handle share the user data
+	h := *a
+	if h.UserData() != "stream-1" {
+		t.Errorf("expected user data on a copy of the handle, got %v", h.UserData())
+	}
+
+	a.Close()
+	if h.UserData() != nil {
+		t.Error("user data was not released on Close")
+	}
+}
+
+func TestUserDataFromListenCallback(t *testing.T) {
+	InitSRT()
+	listener := NewSrtSocket("127.0.0.1", 0, map[string]string{"blocking": "1", "mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	err := listener.SetListenCallback(func(s *SrtSocket, version int, addr *net.UDPAddr, streamid string) bool {
+		s.SetUserData(streamid)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		caller := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": "1", "mode": "caller", "streamid": "cam1"})
+		if caller != nil {
+			caller.Connect()
+			time.Sleep(500 * time.Millisecond)
+			caller.Close()
+		}
+	}()
+	remote, _, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	if remote.UserData() != "cam1" {
+		t.Errorf("expected the user data set in the listen callback, got %v", remote.UserData())
+	}
+}
+
+func encryptionHelper(t *testing.T, listenerPass, callerPass string) error {
+	port := randomPort()
+	lopts := map[string]string{"blocking": "1", "mode": "listener", "enforcedencryption": "1"}
+	copts := map[string]string{"blocking": "1", "mode": "caller", "enforcedencryption": "1", "conntimeo": "1000"}
+	if listenerPass != "" {
+		lopts["passphrase"] = listenerPass
+	}
+	if callerPass != "" {
+		copts["passphrase"] = callerPass
+	}
+
+	listener := NewSrtSocket("127.0.0.1", port, lopts)
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		sock, _, err := listener.Accept()
+		if err == nil {
+			sock.Close()
+		}
+	}()
+
+	caller := NewSrtSocket("127.0.0.1", port, copts)
+	if caller == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer caller.Close()
+	return caller.Connect()
+}
+
+func TestConnectEncryptionErrors(t *testing.T) {
+	InitSRT()
+
+	if err := encryptionHelper(t, "passphrase01", "passphrase01"); err != nil {
+		t.Errorf("matched passphrases should connect, got %v", err)
+	}
+
+	cases := []struct {
+		listenerPass, callerPass string
+		fault                    EncryptionFault
+	}{
+		{"passphrase01", "passphrase02", EncryptionFaultMismatch},
+		{"passphrase01", "", EncryptionFaultLocal},
+		{"", "passphrase01", EncryptionFaultPeer},
+	}
+	for _, c := range cases {
+		err := encryptionHelper(t, c.listenerPass, c.callerPass)
+		var encErr *EncryptionError
+		if !errors.As(err, &encErr) {
+			t.Errorf("listener %q / caller %q: expected EncryptionError, got %v", c.listenerPass, c.callerPass, err)
+			continue
+		}
+		if encErr.Fault != c.fault {
+			t.Errorf("listener %q / caller %q: expected fault %s, got %s", c.listenerPass, c.callerPass, c.fault, encErr.Fault)
+		}
+	}
+}
+
+func TestEncryptionState(t *testing.T) {
+	cases := []struct {
+		name                     string
+		listenerPass, callerPass string
+		send, receive            SrtKmState // expected on the caller
+	}{
+		{"unsecured", "", "", SrtKmStateUnsecured, SrtKmStateUnsecured},
+		{"secured", "passphrase01", "passphrase01", SrtKmStateSecured, SrtKmStateSecured},
+		// Without enforcedencryption a caller without passphrase connects,
+		// sends in the clear and cannot decrypt what it receives
+		{"caller without passphrase", "passphrase01", "", SrtKmStateUnsecured, SrtKmStateNoSecret},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			InitSRT()
+			lopts := map[string]string{"mode": "listener", "enforcedencryption": "0"}
+			copts := map[string]string{"mode": "caller", "enforcedencryption": "0"}
+			if c.listenerPass != "" {
+				lopts["passphrase"] = c.listenerPass
+			}
+			if c.callerPass != "" {
+				copts["passphrase"] = c.callerPass
+			}
+			listener := NewSrtSocket("127.0.0.1", 0, lopts)
+			if listener == nil {
+				t.Fatal("Could not create a srt socket")
+			}
+			defer listener.Close()
+			if err := listener.Listen(1); err != nil {
+				t.Fatal(err)
+			}
+			port, err := listener.BoundPort()
+			if err != nil {
+				t.Fatal(err)
+			}
+			go func() {
+				sock, _, err := listener.Accept()
+				if err == nil {
+					defer sock.Close()
+					time.Sleep(500 * time.Millisecond)
+				}
+			}()
+
+			caller := NewSrtSocket("127.0.0.1", port, copts)
+			if caller == nil {
+				t.Fatal("Could not create a srt socket")
+			}
+			defer caller.Close()
+			if err := caller.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			send, receive, err := caller.EncryptionState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if send != c.send || receive != c.receive {
+				t.Errorf("expected send %s, receive %s, got %s, %s", c.send, c.receive, send, receive)
+			}
+		})
+	}
+
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live", "passphrase": "passphrase01"})
+	defer caller.Close()
+	defer remote.Close()
+	if send, receive, err := remote.EncryptionState(); err != nil || send != SrtKmStateSecured || receive != SrtKmStateSecured {
+		t.Errorf("expected the accepted socket to be secured in both directions, got %s, %s (%v)", send, receive, err)
+	}
+
+	closed := NewSrtSocket("127.0.0.1", 0, map[string]string{})
+	if closed == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	closed.Close()
+	if _, _, err := closed.EncryptionState(); err == nil {
+		t.Error("expected an error for a closed socket")
+	}
+}
+
+func TestReorderTolerance(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if err := remote.SetReorderTolerance(5); err != nil {
+		t.Fatal(err)
+	}
+	tolerance, err := remote.ReorderTolerance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tolerance != 5 {
+		t.Errorf("expected reorder tolerance 5, got %d", tolerance)
+	}
+	if err := remote.SetReorderTolerance(-1); err == nil {
+		t.Error("negative reorder tolerance should be rejected")
+	}
+
+	// Loopback does not reorder packets
+	distance, err := remote.ObservedReorderDistance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if distance != 0 {
+		t.Errorf("expected no reordering on loopback, got distance %d", distance)
+	}
+}
+
+func TestPeerAddressChangeCallback(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	addr, err := remote.PeerAddr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !addr.IP.IsLoopback() {
+		t.Errorf("expected loopback peer address, got %s", addr)
+	}
+
+	var changes int32
+	err = remote.SetPeerAddressChangeCallback(func(old, new net.Addr) {
+		atomic.AddInt32(&changes, 1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := caller.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Read(make([]byte, 1500)); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&changes); n != 0 {
+		t.Errorf("callback fired %d times without an address change", n)
+	}
+
+	remote.SetPeerAddressChangeCallback(nil)
+	if atomic.LoadInt32(&remote.state.peerAddrWatched) != 0 {
+		t.Error("expected the watch to be removed")
+	}
+}
+
+func TestEncryptionStateEvents(t *testing.T) {
+	options := map[string]string{"transtype": "live", "passphrase": "0123456789abcdef"}
+	caller, remote := connectedPair(t, options)
+	defer remote.Close()
+
+	events := caller.EncryptionStateEvents(10 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	caller.Close()
+
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			t.Errorf("unexpected transition on a secured connection: %+v", ev)
+		case <-time.After(time.Second):
+			t.Fatal("events channel was not closed after Close")
+		}
+	}
+}
+
+func TestBoundPortEphemeral(t *testing.T) {
+	InitSRT()
+	listener := NewSrtSocket("127.0.0.1", 0, map[string]string{"mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port == 0 {
+		t.Error("expected an ephemeral port to be assigned")
+	}
+
+	caller := NewSrtSocket("127.0.0.1", 0, map[string]string{"mode": "caller"})
+	if caller == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer caller.Close()
+	if err := caller.Connect(); err == nil {
+		t.Error("connecting to port 0 should fail")
+	}
+}
+
+func TestPendingConnections(t *testing.T) {
+	InitSRT()
+	listener := NewSrtSocket("127.0.0.1", 0, map[string]string{"mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if _, err := listener.PendingConnections(); err == nil {
+		t.Error("expected error before Listen")
+	}
+	// Connections are tracked from the listen callback
+	if err := listener.SetListenCallback(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := listener.Listen(4); err != nil {
+		t.Fatal(err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caller := NewSrtSocket("127.0.0.1", port, map[string]string{"mode": "caller"})
+	if caller == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer caller.Close()
+	if err := caller.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := listener.PendingConnections(); err != nil || n != 1 {
+		t.Errorf("expected 1 pending connection, got %d (%v)", n, err)
+	}
+
+	other := NewSrtSocket("127.0.0.1", port, map[string]string{"mode": "caller"})
+	if other == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	if err := other.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := listener.PendingConnections(); err != nil || n != 2 {
+		t.Errorf("expected 2 pending connections, got %d (%v)", n, err)
+	}
+
+	remote, _, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	if remote.ConnectDuration() <= 0 {
+		t.Errorf("expected a connect duration measured from the listen callback, got %s", remote.ConnectDuration())
+	}
+
+	// The connection that was not accepted stops counting once it breaks
+	other.Close()
+	caller.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n, err := listener.PendingConnections()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected no pending connection, got %d", n)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestPendingConnectionsPruned(t *testing.T) {
+	InitSRT()
+	options := map[string]string{"mode": "listener", "passphrase": "passphrase01", "enforcedencryption": "1"}
+	listener := NewSrtSocket("127.0.0.1", 0, options)
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.SetListenCallback(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := listener.Listen(4); err != nil {
+		t.Fatal(err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Admitted by the listen callback, then rejected on the passphrase
+	bad := NewSrtSocket("127.0.0.1", port, map[string]string{"mode": "caller", "passphrase": "passphrase02", "conntimeo": "1000"})
+	if bad == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer bad.Close()
+	if err := bad.Connect(); err == nil {
+		t.Fatal("expected the wrong passphrase to be rejected")
+	}
+	time.Sleep(pendingRegisterGrace + 100*time.Millisecond)
+
+	caller := NewSrtSocket("127.0.0.1", port, map[string]string{"mode": "caller", "passphrase": "passphrase01"})
+	if caller == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer caller.Close()
+	if err := caller.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	remote, _, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	// Without PendingConnections, admitting and accepting forget the failure
+	listener.state.mu.Lock()
+	n := len(listener.state.backlog.pending)
+	listener.state.mu.Unlock()
+	if n != 0 {
+		t.Errorf("expected no pending state left, got %d", n)
+	}
+}
+
+func TestPendingConnectionsWithoutCallback(t *testing.T) {
+	InitSRT()
+	listener := NewSrtSocket("127.0.0.1", 0, map[string]string{"mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	callbackMutex.Lock()
+	_, installed := listenCallbackMap[listener.socket]
+	callbackMutex.Unlock()
+	if installed {
+		t.Error("Listen installed a listen callback")
+	}
+	if _, err := listener.PendingConnections(); err == nil {
+		t.Error("expected error without a listen callback")
+	}
+}
+
+func TestConnectDeadlineUnresolvable(t *testing.T) {
+	// A resolver that never answers, like an unreachable DNS server
+	resolving := make(chan struct{}, 1)
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		resolving <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	defer func() { lookupIPAddr = net.DefaultResolver.LookupIPAddr }()
+
+	InitSRT()
+	s := NewSrtSocket("unresolvable.example", 8090, map[string]string{"mode": "caller"})
+	if s == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer s.Close()
+
+	timeout := 500 * time.Millisecond
+	start := time.Now()
+	err := s.ConnectDeadline(start.Add(timeout))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end the resolution, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > timeout+100*time.Millisecond {
+		t.Errorf("ConnectDeadline took %s, deadline was %s", elapsed, timeout)
+	}
+	select {
+	case <-resolving:
+	default:
+		t.Error("the host name was not resolved")
+	}
+}
+
+func TestIdleTimeoutCallback(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live", "peeridletimeo": "1000"})
+	defer remote.Close()
+
+	broken := make(chan *SrtSocket, 1)
+	if err := remote.SetIdleTimeoutCallback(func(s *SrtSocket) { broken <- s }); err != nil {
+		t.Fatal(err)
+	}
+	caller.Close()
+
+	select {
+	case s := <-broken:
+		if s.socket != remote.socket || s.pd != remote.pd {
+			t.Errorf("callback called with socket %d, expected the handle of %d", s.socket, remote.socket)
+		}
+		// Closing the handle passed to the callback releases the socket
+		s.Close()
+		if isRegistered(remote) {
+			t.Error("socket still registered with the poll server after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle timeout callback not called")
+	}
+}
+
+func TestEffectiveLatency(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{"latency": "250"})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+	if _, err := a.EffectiveReceiveLatency(); err == nil {
+		t.Error("expected error before the handshake")
+	}
+
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live", "latency": "250"})
+	defer caller.Close()
+	defer remote.Close()
+	if d, err := caller.EffectiveReceiveLatency(); err != nil || d != 250*time.Millisecond {
+		t.Errorf("expected receive latency 250ms, got %s (%v)", d, err)
+	}
+	if d, err := caller.EffectivePeerLatency(); err != nil || d != 250*time.Millisecond {
+		t.Errorf("expected peer latency 250ms, got %s (%v)", d, err)
+	}
+}
+
+func TestCheckUDPBufferSizes(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{"udp_rcvbuf": "262144"})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+
+	_, rcv, err := a.UDPBufferSizes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcv != 262144 {
+		t.Errorf("expected udp_rcvbuf 262144, got %d", rcv)
+	}
+	_, rcvCheck, err := a.CheckUDPBufferSizes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcvCheck.Requested != 262144 || rcvCheck.Effective <= 0 {
+		t.Errorf("unexpected receive buffer check %+v", rcvCheck)
+	}
+	if rcvCheck.Capped != (rcvCheck.Effective < rcvCheck.Requested) {
+		t.Errorf("inconsistent receive buffer check %+v", rcvCheck)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live", "streamid": "#!::r=live/cam1"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if err := caller.SetMaxBandwidth(BWModeAbsolute, 8000000, 0); err != nil {
+		t.Fatal(err)
+	}
+	state, err := caller.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Options["streamid"] != "#!::r=live/cam1" {
+		t.Errorf("streamid not preserved: %q", state.Options["streamid"])
+	}
+	if state.Options["maxbw"] != "1000000" {
+		t.Errorf("runtime maxbw not preserved: %q", state.Options["maxbw"])
+	}
+	if state.Options["mode"] != "caller" || state.Options["transtype"] != "live" {
+		t.Errorf("creation options not preserved: %v", state.Options)
+	}
+	if state.Port != caller.port {
+		t.Errorf("expected port %d, got %d", caller.port, state.Port)
+	}
+}
+
+func TestServe(t *testing.T) {
+	InitSRT()
+	listener := NewSrtSocket("127.0.0.1", 0, map[string]string{"mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	if err := listener.Listen(4); err != nil {
+		t.Fatal(err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, maxRunning, handled int32
+	release := make(chan struct{})
+	served := make(chan error, 1)
+	go func() {
+		served <- listener.Serve(func(s *SrtSocket) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&handled, 1)
+		}, 2)
+	}()
+
+	for i := 0; i < 3; i++ {
+		c := NewSrtSocket("127.0.0.1", port, map[string]string{"mode": "caller"})
+		if c == nil {
+			t.Fatal("Could not create a srt socket")
+		}
+		defer c.Close()
+		go c.Connect()
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt32(&running); n != 2 {
+		t.Errorf("expected 2 concurrent handlers, got %d", n)
+	}
+
+	listener.Close()
+	close(release)
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+	if atomic.LoadInt32(&running) != 0 {
+		t.Error("Serve returned before handlers finished")
+	}
+	if max := atomic.LoadInt32(&maxRunning); max > 2 {
+		t.Errorf("concurrency limit exceeded: %d handlers", max)
+	}
+}
+
+func TestServeRetriesAcceptErrors(t *testing.T) {
+	InitSRT()
+	listener := NewSrtSocket("127.0.0.1", 0, map[string]string{"mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	// Every Accept times out, which must not end Serve
+	listener.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+
+	served := make(chan error, 1)
+	go func() {
+		served <- listener.Serve(func(s *SrtSocket) {}, 1)
+	}()
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned on a temporary error: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	listener.Close()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+}
+
+// connectedPairIPv6 connects two sockets over the IPv6 loopback, skipping the
+// test if IPv6 is not available
+func connectedPairIPv6(t *testing.T, options map[string]string) (*SrtSocket, *SrtSocket) {
+	InitSRT()
+	listenOptions := map[string]string{"mode": "listener", "blocking": "1"}
+	callerOptions := map[string]string{"mode": "caller", "blocking": "1"}
+	for k, v := range options {
+		listenOptions[k] = v
+		callerOptions[k] = v
+	}
+	listener := NewSrtSocket("::1", 0, listenOptions)
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan *SrtSocket, 1)
+	go func() {
+		remote, _, err := listener.Accept()
+		if err != nil {
+			remote = nil
+		}
+		accepted <- remote
+	}()
+	caller := NewSrtSocket("::1", port, callerOptions)
+	if caller == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	if err := caller.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	remote := <-accepted
+	if remote == nil {
+		caller.Close()
+		t.Fatal("accept over IPv6 failed")
+	}
+	return caller, remote
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	// File mode sets no payload size, so it is derived from the MSS
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file"})
+	size, err := caller.MaxPayloadSize()
+	caller.Close()
+	remote.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != defaultMSS-44 {
+		t.Errorf("expected %d bytes over IPv4, got %d", defaultMSS-44, size)
+	}
+
+	caller, remote = connectedPairIPv6(t, map[string]string{"transtype": "file"})
+	defer caller.Close()
+	defer remote.Close()
+	for _, s := range []*SrtSocket{caller, remote} {
+		size, err := s.MaxPayloadSize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != defaultMSS-64 {
+			t.Errorf("expected %d bytes over IPv6, got %d", defaultMSS-64, size)
+		}
+	}
+}
+
+func TestBufferOccupancy(t *testing.T) {
+	// Message mode without TSBPD, so received messages stay in the receive
+	// buffer until they are read
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "messageapi": "1"})
+	defer caller.Close()
+	defer remote.Close()
+
+	occupancy := func(s *SrtSocket) (sndPkts, sndBytes, rcvPkts, rcvBytes int) {
+		var err error
+		if sndPkts, err = s.SendBufferPackets(); err != nil {
+			t.Fatal(err)
+		}
+		if sndBytes, err = s.SendBufferBytes(); err != nil {
+			t.Fatal(err)
+		}
+		if rcvPkts, err = s.ReceiveBufferPackets(); err != nil {
+			t.Fatal(err)
+		}
+		if rcvBytes, err = s.ReceiveBufferBytes(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	for _, s := range []*SrtSocket{caller, remote} {
+		if sp, sb, rp, rb := occupancy(s); sp != 0 || sb != 0 || rp != 0 || rb != 0 {
+			t.Errorf("expected empty buffers on an idle socket, got %d/%d packets, %d/%d bytes", sp, rp, sb, rb)
+		}
+	}
+
+	const messages, size = 10, 1000
+	payload := make([]byte, size)
+	for i := 0; i < messages; i++ {
+		if _, err := caller.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, func() bool {
+		n, err := remote.ReceiveBufferPackets()
+		return err == nil && n == messages
+	})
+	if _, _, _, rb := occupancy(remote); rb != messages*size {
+		t.Errorf("expected %d bytes in the receive buffer, got %d", messages*size, rb)
+	}
+	// Acknowledged packets leave the send buffer
+	waitFor(t, func() bool {
+		sp, sb, _, _ := occupancy(caller)
+		return sp == 0 && sb == 0
+	})
+
+	buf := make([]byte, size)
+	remote.SetReadDeadline(time.Now().Add(time.Second))
+	for i := 0; i < messages; i++ {
+		if _, err := remote.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, rp, rb := occupancy(remote); rp != 0 || rb != 0 {
+		t.Errorf("expected an empty receive buffer after reading, got %d packets, %d bytes", rp, rb)
+	}
+}
+
+func TestGroupStatsSingleSocket(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if caller.IsGroup() {
+		t.Error("a single connection is not a group")
+	}
+	if _, err := caller.GroupStats(); err == nil {
+		t.Error("expected GroupStats to fail on a single connection")
+	}
+	if _, err := caller.MemberWeights(); err == nil {
+		t.Error("expected MemberWeights to fail on a single connection")
+	}
+	if _, err := remote.GroupMembers(); err == nil {
+		t.Error("expected GroupMembers to fail on a single accepted connection")
+	}
+	if _, err := remote.GroupID(); err == nil {
+		t.Error("expected GroupID to fail for a socket outside a group")
+	}
+}
+
+func TestConnectBindFailure(t *testing.T) {
+	InitSRT()
+	// A UDP socket that never answers the handshake
+	silent, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	port := uint16(silent.LocalAddr().(*net.UDPAddr).Port)
+
+	for _, blocking := range []string{"1", "0"} {
+		caller := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": blocking, "mode": "caller", "conntimeo": "500"})
+		if caller == nil {
+			t.Fatal("Could not create a srt socket")
+		}
+		socket, closed := caller.socket, caller.closed
+		if _, err := caller.ConnectBind("127.0.0.1", 0, "127.0.0.1", port); err == nil {
+			t.Fatalf("blocking=%s: expected the connection to fail", blocking)
+		}
+		if caller.socket != SRT_INVALID_SOCK || atomic.LoadInt32(closed) != 1 {
+			t.Errorf("blocking=%s: expected the socket to be closed", blocking)
+		}
+		if blocking == "0" {
+			p := pollServerCtx()
+			p.pollDescLock.Lock()
+			_, registered := p.pollDescs[socket]
+			p.pollDescLock.Unlock()
+			if registered {
+				t.Error("socket still registered with the poll server")
+			}
+		}
+		if err := caller.Close(); err != nil {
+			t.Errorf("blocking=%s: closing again failed: %v", blocking, err)
+		}
+	}
+}
+
+func TestConnectBind(t *testing.T) {
+	InitSRT()
+	listener := NewSrtSocket("127.0.0.1", 0, map[string]string{"blocking": "1", "mode": "listener"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+	if err := listener.Listen(1); err != nil {
+		t.Fatal(err)
+	}
+	port, err := listener.BoundPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := make(chan *net.UDPAddr, 1)
+	go func() {
+		sock, addr, err := listener.Accept()
+		if err == nil {
+			peer <- addr
+			sock.Close()
+		}
+	}()
+
+	caller := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": "1", "mode": "caller", "conntimeo": "1000"})
+	if caller == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer caller.Close()
+	if _, err := caller.ConnectBind("::1", 0, "127.0.0.1", port); err == nil {
+		t.Error("expected mixed address families to be rejected")
+	}
+	local, err := caller.ConnectBind("127.0.0.1", 0, "127.0.0.1", port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local == 0 {
+		t.Error("expected the local port chosen by the OS")
+	}
+	select {
+	case addr := <-peer:
+		if addr.Port != int(local) {
+			t.Errorf("listener saw port %d, ConnectBind reported %d", addr.Port, local)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection not accepted")
+	}
+}
+
+func TestConnectDuration(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+	if d := a.ConnectDuration(); d != 0 {
+		t.Errorf("expected 0 before connecting, got %s", d)
+	}
+
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+	if d := caller.ConnectDuration(); d <= 0 || d > 5*time.Second {
+		t.Errorf("unexpected caller connect duration %s", d)
+	}
+	// Without a listen callback the accepted side has millisecond resolution
+	if d := remote.ConnectDuration(); d < 0 || d > 5*time.Second {
+		t.Errorf("unexpected accepted connect duration %s", d)
+	}
+}
+
+func TestSenderDroppedPackets(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if err := caller.SetSendDropDelay(100 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := caller.SetSendDropDelay(-1); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := caller.SenderDroppedPackets(); err != nil || n != 0 {
+		t.Errorf("expected no dropped packets on an idle connection, got %d (%v)", n, err)
+	}
+}
+
+func TestWindows(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "file", "fc": "4096"})
+	defer caller.Close()
+	defer remote.Close()
+
+	if _, err := caller.Write(make([]byte, 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Read(make([]byte, 1500)); err != nil {
+		t.Fatal(err)
+	}
+	if w, err := caller.FlowWindow(); err != nil || w <= 0 || w > 4096 {
+		t.Errorf("expected a flow window within fc, got %d (%v)", w, err)
+	}
+	if w, err := caller.CongestionWindow(); err != nil || w <= 0 {
+		t.Errorf("expected a positive congestion window, got %d (%v)", w, err)
+	}
+}
+
+func TestListenCallbackPassphrase(t *testing.T) {
+	InitSRT()
+
+	port := randomPort()
+	listener := NewSrtSocket("127.0.0.1", port, map[string]string{"blocking": "1", "mode": "listener", "enforcedencryption": "1"})
+	if listener == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer listener.Close()
+
+	// Each tenant gets its own passphrase, chosen by streamid
+	passphrases := map[string]string{"tenant-a": "passphrase-a1", "tenant-b": "passphrase-b1"}
+	callbackErr := make(chan error, 2)
+	err := listener.SetListenCallback(func(s *SrtSocket, version int, addr *net.UDPAddr, streamid string) bool {
+		pass, ok := passphrases[streamid]
+		if !ok {
+			return false
+		}
+		if err := s.SetPassphrase(pass); err != nil {
+			callbackErr <- err
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := listener.Listen(2); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			sock, _, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			sock.Close()
+		}
+	}()
+
+	connect := func(streamid, pass string) error {
+		caller := NewSrtSocket("127.0.0.1", port, map[string]string{
+			"blocking": "1", "mode": "caller", "enforcedencryption": "1", "conntimeo": "1000",
+			"streamid": streamid, "passphrase": pass,
+		})
+		if caller == nil {
+			t.Fatal("Could not create a srt socket")
+		}
+		defer caller.Close()
+		return caller.Connect()
+	}
+
+	if err := connect("tenant-a", "passphrase-a1"); err != nil {
+		t.Errorf("tenant-a with its passphrase should connect, got %v", err)
+	}
+	select {
+	case err := <-callbackErr:
+		t.Fatalf("setting the passphrase from the listen callback failed: %v", err)
+	default:
+	}
+	if err := connect("tenant-a", "passphrase-b1"); err == nil {
+		t.Error("tenant-a with the passphrase of tenant-b should be rejected")
+	}
+}
@@ -0,0 +1,160 @@
+// Package srtprometheus exports the statistics of SRT sockets as Prometheus
+// metrics. It is a separate module, so that users of srtgo who do not need it
+// are not required to depend on the Prometheus client library.
+package srtprometheus
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/haivision/srtgo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Labels of every metric: the SRT socket id, which tells the series of
+// sockets with the same streamid apart, and the streamid given to Add
+const (
+	socketLabel   = "socket"
+	streamIDLabel = "streamid"
+)
+
+// Collector implements prometheus.Collector for a set of SRT sockets. Every
+// scrape reads the statistics of each registered socket without resetting
+// the interval counters (StatsNoClear), so it does not interfere with other
+// users of Stats. Sockets whose statistics cannot be read, e.g. because they
+// have been closed, are skipped. Several handles of the same socket are
+// reported once.
+type Collector struct {
+	mu      sync.Mutex
+	sockets map[*srtgo.SrtSocket]string
+
+	rtt            *prometheus.Desc
+	bandwidth      *prometheus.Desc
+	sendRate       *prometheus.Desc
+	recvRate       *prometheus.Desc
+	sentPackets    *prometheus.Desc
+	recvPackets    *prometheus.Desc
+	sentBytes      *prometheus.Desc
+	recvBytes      *prometheus.Desc
+	sndLossPackets *prometheus.Desc
+	rcvLossPackets *prometheus.Desc
+	retransPackets *prometheus.Desc
+	sndDropPackets *prometheus.Desc
+	rcvDropPackets *prometheus.Desc
+	sndBufPackets  *prometheus.Desc
+	sndBufBytes    *prometheus.Desc
+	sndBufMs       *prometheus.Desc
+	rcvBufPackets  *prometheus.Desc
+	rcvBufBytes    *prometheus.Desc
+	rcvBufMs       *prometheus.Desc
+}
+
+// NewCollector creates a Collector whose metric names start with namespace,
+// e.g. "srt" gives srt_rtt_seconds
+func NewCollector(namespace string) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, []string{socketLabel, streamIDLabel}, nil)
+	}
+	return &Collector{
+		sockets: make(map[*srtgo.SrtSocket]string),
+
+		rtt:            desc("rtt_seconds", "Smoothed round trip time."),
+		bandwidth:      desc("bandwidth_bits_per_second", "Estimated link bandwidth."),
+		sendRate:       desc("send_rate_bits_per_second", "Sending rate."),
+		recvRate:       desc("receive_rate_bits_per_second", "Receiving rate."),
+		sentPackets:    desc("sent_packets_total", "Data packets sent, including retransmissions."),
+		recvPackets:    desc("received_packets_total", "Data packets received."),
+		sentBytes:      desc("sent_bytes_total", "Data bytes sent, including retransmissions."),
+		recvBytes:      desc("received_bytes_total", "Data bytes received."),
+		sndLossPackets: desc("send_lost_packets_total", "Packets reported lost by the receiver."),
+		rcvLossPackets: desc("receive_lost_packets_total", "Packets detected missing by the receiver."),
+		retransPackets: desc("retransmitted_packets_total", "Packets retransmitted."),
+		sndDropPackets: desc("send_dropped_packets_total", "Packets dropped by the sender as too late to send."),
+		rcvDropPackets: desc("receive_dropped_packets_total", "Packets dropped by the receiver as too late to play."),
+		sndBufPackets:  desc("send_buffer_packets", "Unacknowledged packets in the send buffer."),
+		sndBufBytes:    desc("send_buffer_bytes", "Unacknowledged bytes in the send buffer."),
+		sndBufMs:       desc("send_buffer_seconds", "Timespan of the unacknowledged data in the send buffer."),
+		rcvBufPackets:  desc("receive_buffer_packets", "Undelivered packets in the receive buffer."),
+		rcvBufBytes:    desc("receive_buffer_bytes", "Undelivered bytes in the receive buffer."),
+		rcvBufMs:       desc("receive_buffer_seconds", "Timespan of the undelivered data in the receive buffer."),
+	}
+}
+
+// Add registers a socket, labelled with its socket id and streamID. The
+// streamid does not need to be unique. Registering a socket again replaces its
+// streamid.
+func (c *Collector) Add(s *srtgo.SrtSocket, streamID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sockets[s] = streamID
+}
+
+// Remove unregisters a socket. Sockets should be removed when closed.
+func (c *Collector) Remove(s *srtgo.SrtSocket) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sockets, s)
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.rtt, c.bandwidth, c.sendRate, c.recvRate,
+		c.sentPackets, c.recvPackets, c.sentBytes, c.recvBytes,
+		c.sndLossPackets, c.rcvLossPackets, c.retransPackets, c.sndDropPackets, c.rcvDropPackets,
+		c.sndBufPackets, c.sndBufBytes, c.sndBufMs, c.rcvBufPackets, c.rcvBufBytes, c.rcvBufMs,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	sockets := make(map[*srtgo.SrtSocket]string, len(c.sockets))
+	for s, id := range c.sockets {
+		sockets[s] = id
+	}
+	c.mu.Unlock()
+
+	const mbps = 1000 * 1000
+	seen := make(map[int]bool, len(sockets))
+	for s, id := range sockets {
+		socket := int(s.GetSocket())
+		if seen[socket] {
+			continue
+		}
+		stats, err := s.StatsNoClear()
+		if err != nil {
+			continue
+		}
+		seen[socket] = true
+		labels := []string{strconv.Itoa(socket), id}
+		gauge := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, labels...)
+		}
+		counter := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v, labels...)
+		}
+
+		gauge(c.rtt, stats.MsRTT/1000)
+		gauge(c.bandwidth, stats.MbpsBandwidth*mbps)
+		gauge(c.sendRate, stats.MbpsSendRate*mbps)
+		gauge(c.recvRate, stats.MbpsRecvRate*mbps)
+		counter(c.sentPackets, float64(stats.PktSentTotal))
+		counter(c.recvPackets, float64(stats.PktRecvTotal))
+		counter(c.sentBytes, float64(stats.ByteSentTotal))
+		counter(c.recvBytes, float64(stats.ByteRecvTotal))
+		counter(c.sndLossPackets, float64(stats.PktSndLossTotal))
+		counter(c.rcvLossPackets, float64(stats.PktRcvLossTotal))
+		counter(c.retransPackets, float64(stats.PktRetransTotal))
+		counter(c.sndDropPackets, float64(stats.PktSndDropTotal))
+		counter(c.rcvDropPackets, float64(stats.PktRcvDropTotal))
+		gauge(c.sndBufPackets, float64(stats.PktSndBuf))
+		gauge(c.sndBufBytes, float64(stats.ByteSndBuf))
+		gauge(c.sndBufMs, float64(stats.MsSndBuf)/1000)
+		gauge(c.rcvBufPackets, float64(stats.PktRcvBuf))
+		gauge(c.rcvBufBytes, float64(stats.ByteRcvBuf))
+		gauge(c.rcvBufMs, float64(stats.MsRcvBuf)/1000)
+	}
+}
@@ -0,0 +1,49 @@
+package srtprometheus
+
+import (
+	"testing"
+
+	"github.com/haivision/srtgo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	srtgo.InitSRT()
+	defer srtgo.CleanupSRT()
+	caller, remote, err := srtgo.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer caller.Close()
+	defer remote.Close()
+
+	c := NewCollector("srt")
+	// Both ends share a streamid, a copy of a handle is reported once
+	c.Add(caller, "live/cam1")
+	c.Add(remote, "live/cam1")
+	copied := *remote
+	c.Add(&copied, "live/cam1")
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Gather(); err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	if n := testutil.CollectAndCount(c, "srt_rtt_seconds"); n != 2 {
+		t.Errorf("expected 2 rtt series, got %d", n)
+	}
+
+	c.Remove(caller)
+	if n := testutil.CollectAndCount(c, "srt_rtt_seconds"); n != 1 {
+		t.Errorf("expected 1 rtt series after Remove, got %d", n)
+	}
+
+	// Closed sockets are skipped
+	remote.Close()
+	if n := testutil.CollectAndCount(c, "srt_rtt_seconds"); n != 0 {
+		t.Errorf("expected no series for a closed socket, got %d", n)
+	}
+}
@@ -0,0 +1,16 @@
+module github.com/haivision/srtgo/srtprometheus
+
+go 1.12
+
+require (
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/haivision/srtgo v0.0.0-00010101000000-000000000000
+	github.com/prometheus/client_golang v1.11.0
+	github.com/prometheus/common v0.32.1 // indirect
+	github.com/prometheus/procfs v0.7.3 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
+)
+
+replace github.com/haivision/srtgo => ../
@@ -7,9 +7,15 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"math"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -25,43 +31,55 @@ const (
 	tBoolean   = 3
 	tTransType = 4
 
-	SRTO_TRANSTYPE          = C.SRTO_TRANSTYPE
-	SRTO_MAXBW              = C.SRTO_MAXBW
-	SRTO_PBKEYLEN           = C.SRTO_PBKEYLEN
-	SRTO_PASSPHRASE         = C.SRTO_PASSPHRASE
-	SRTO_MSS                = C.SRTO_MSS
-	SRTO_FC                 = C.SRTO_FC
-	SRTO_SNDBUF             = C.SRTO_SNDBUF
-	SRTO_RCVBUF             = C.SRTO_RCVBUF
-	SRTO_IPTTL              = C.SRTO_IPTTL
-	SRTO_IPTOS              = C.SRTO_IPTOS
-	SRTO_INPUTBW            = C.SRTO_INPUTBW
-	SRTO_OHEADBW            = C.SRTO_OHEADBW
-	SRTO_LATENCY            = C.SRTO_LATENCY
-	SRTO_TSBPDMODE          = C.SRTO_TSBPDMODE
-	SRTO_TLPKTDROP          = C.SRTO_TLPKTDROP
-	SRTO_SNDDROPDELAY       = C.SRTO_SNDDROPDELAY
-	SRTO_NAKREPORT          = C.SRTO_NAKREPORT
-	SRTO_CONNTIMEO          = C.SRTO_CONNTIMEO
-	SRTO_LOSSMAXTTL         = C.SRTO_LOSSMAXTTL
-	SRTO_RCVLATENCY         = C.SRTO_RCVLATENCY
-	SRTO_PEERLATENCY        = C.SRTO_PEERLATENCY
-	SRTO_MINVERSION         = C.SRTO_MINVERSION
-	SRTO_STREAMID           = C.SRTO_STREAMID
-	SRTO_CONGESTION         = C.SRTO_CONGESTION
-	SRTO_MESSAGEAPI         = C.SRTO_MESSAGEAPI
-	SRTO_PAYLOADSIZE        = C.SRTO_PAYLOADSIZE
-	SRTO_KMREFRESHRATE      = C.SRTO_KMREFRESHRATE
-	SRTO_KMPREANNOUNCE      = C.SRTO_KMPREANNOUNCE
-	SRTO_ENFORCEDENCRYPTION = C.SRTO_ENFORCEDENCRYPTION
-	SRTO_PEERIDLETIMEO      = C.SRTO_PEERIDLETIMEO
-	SRTO_PACKETFILTER       = C.SRTO_PACKETFILTER
-	SRTO_STATE              = C.SRTO_STATE
-	SRTO_UDP_RCVBUF         = C.SRTO_UDP_RCVBUF
-	SRTO_UDP_SNDBUF         = C.SRTO_UDP_SNDBUF
-	SRTO_MININPUTBW         = C.SRTO_MININPUTBW
-	SRTO_SENDER             = C.SRTO_SENDER
-	SRTO_REUSEADDR          = C.SRTO_REUSEADDR
+	SRTO_TRANSTYPE           = C.SRTO_TRANSTYPE
+	SRTO_MAXBW               = C.SRTO_MAXBW
+	SRTO_PBKEYLEN            = C.SRTO_PBKEYLEN
+	SRTO_PASSPHRASE          = C.SRTO_PASSPHRASE
+	SRTO_MSS                 = C.SRTO_MSS
+	SRTO_FC                  = C.SRTO_FC
+	SRTO_SNDBUF              = C.SRTO_SNDBUF
+	SRTO_RCVBUF              = C.SRTO_RCVBUF
+	SRTO_IPTTL               = C.SRTO_IPTTL
+	SRTO_IPTOS               = C.SRTO_IPTOS
+	SRTO_INPUTBW             = C.SRTO_INPUTBW
+	SRTO_OHEADBW             = C.SRTO_OHEADBW
+	SRTO_LATENCY             = C.SRTO_LATENCY
+	SRTO_TSBPDMODE           = C.SRTO_TSBPDMODE
+	SRTO_TLPKTDROP           = C.SRTO_TLPKTDROP
+	SRTO_SNDDROPDELAY        = C.SRTO_SNDDROPDELAY
+	SRTO_NAKREPORT           = C.SRTO_NAKREPORT
+	SRTO_CONNTIMEO           = C.SRTO_CONNTIMEO
+	SRTO_LOSSMAXTTL          = C.SRTO_LOSSMAXTTL
+	SRTO_RCVLATENCY          = C.SRTO_RCVLATENCY
+	SRTO_PEERLATENCY         = C.SRTO_PEERLATENCY
+	SRTO_MINVERSION          = C.SRTO_MINVERSION
+	SRTO_STREAMID            = C.SRTO_STREAMID
+	SRTO_CONGESTION          = C.SRTO_CONGESTION
+	SRTO_MESSAGEAPI          = C.SRTO_MESSAGEAPI
+	SRTO_PAYLOADSIZE         = C.SRTO_PAYLOADSIZE
+	SRTO_KMREFRESHRATE       = C.SRTO_KMREFRESHRATE
+	SRTO_KMPREANNOUNCE       = C.SRTO_KMPREANNOUNCE
+	SRTO_ENFORCEDENCRYPTION  = C.SRTO_ENFORCEDENCRYPTION
+	SRTO_PEERIDLETIMEO       = C.SRTO_PEERIDLETIMEO
+	SRTO_PACKETFILTER        = C.SRTO_PACKETFILTER
+	SRTO_STATE               = C.SRTO_STATE
+	SRTO_UDP_RCVBUF          = C.SRTO_UDP_RCVBUF
+	SRTO_UDP_SNDBUF          = C.SRTO_UDP_SNDBUF
+	SRTO_MININPUTBW          = C.SRTO_MININPUTBW
+	SRTO_SENDER              = C.SRTO_SENDER
+	SRTO_REUSEADDR           = C.SRTO_REUSEADDR
+	SRTO_GROUPCONNECT        = C.SRTO_GROUPCONNECT
+	SRTO_GROUPMINSTABLETIMEO = C.SRTO_GROUPMINSTABLETIMEO
+	SRTO_EVENT               = C.SRTO_EVENT
+	SRTO_SNDDATA             = C.SRTO_SNDDATA
+	SRTO_RCVDATA             = C.SRTO_RCVDATA
+	SRTO_SNDKMSTATE          = C.SRTO_SNDKMSTATE
+	SRTO_RCVKMSTATE          = C.SRTO_RCVKMSTATE
+	SRTO_IPV6ONLY            = C.SRTO_IPV6ONLY
+	SRTO_PEERVERSION         = C.SRTO_PEERVERSION
+	SRTO_BINDTODEVICE        = C.SRTO_BINDTODEVICE
+	SRTO_SNDTIMEO            = C.SRTO_SNDTIMEO
+	SRTO_RCVTIMEO            = C.SRTO_RCVTIMEO
 )
 
 type socketOption struct {
@@ -70,8 +88,18 @@ type socketOption struct {
 	option    int
 	lifecycle SrtOptionLifecycle // EXPLICIT lifecycle property - single source of truth
 	dataType  int
+	order     int // precedence within a lifecycle stage, see optionApplyOrder
 }
 
+// Application order of options within a lifecycle stage: options with a lower
+// order are set first, so that an option that changes the value of others is
+// set before them and does not clobber their configured values
+const (
+	orderFirst   = -2 // transtype resets the options it has defaults for
+	orderEarly   = -1 // latency sets both rcvlatency and peerlatency
+	orderDefault = 0
+)
+
 // Name returns the option name (accessor for external use)
 func (so socketOption) Name() string {
 	return so.name
@@ -102,48 +130,130 @@ func (so socketOption) CanSetAt(stage SrtOptionLifecycle) bool {
 var SocketOptions = []socketOption{
 	// ===== PREBIND OPTIONS (SRTO_R_PREBIND) =====
 	// These affect buffer allocation and binding behavior
-	{"mss", 0, SRTO_MSS, LifecyclePrebind, tInteger32},
-	{"sndbuf", 0, SRTO_SNDBUF, LifecyclePrebind, tInteger32},
-	{"rcvbuf", 0, SRTO_RCVBUF, LifecyclePrebind, tInteger32},
-	{"udp_sndbuf", 0, SRTO_UDP_SNDBUF, LifecyclePrebind, tInteger32},
-	{"udp_rcvbuf", 0, SRTO_UDP_RCVBUF, LifecyclePrebind, tInteger32},
-	{"ipttl", 0, SRTO_IPTTL, LifecyclePrebind, tInteger32},
-	{"iptos", 0, SRTO_IPTOS, LifecyclePrebind, tInteger32},
-	{"reuseaddr", 0, SRTO_REUSEADDR, LifecyclePrebind, tBoolean},
-	{"transtype", 0, SRTO_TRANSTYPE, LifecyclePrebind, tTransType},
+	{"mss", 0, SRTO_MSS, LifecyclePrebind, tInteger32, orderDefault},
+	{"sndbuf", 0, SRTO_SNDBUF, LifecyclePrebind, tInteger32, orderDefault},
+	{"rcvbuf", 0, SRTO_RCVBUF, LifecyclePrebind, tInteger32, orderDefault},
+	{"udp_sndbuf", 0, SRTO_UDP_SNDBUF, LifecyclePrebind, tInteger32, orderDefault},
+	{"udp_rcvbuf", 0, SRTO_UDP_RCVBUF, LifecyclePrebind, tInteger32, orderDefault},
+	{"ipttl", 0, SRTO_IPTTL, LifecyclePrebind, tInteger32, orderDefault},
+	{"iptos", 0, SRTO_IPTOS, LifecyclePrebind, tInteger32, orderDefault},
+	{"reuseaddr", 0, SRTO_REUSEADDR, LifecyclePrebind, tBoolean, orderDefault},
+	{"transtype", 0, SRTO_TRANSTYPE, LifecyclePrebind, tTransType, orderFirst},
+	{"ipv6only", 0, SRTO_IPV6ONLY, LifecyclePrebind, tInteger32, orderDefault},
+	{"bindtodevice", 0, SRTO_BINDTODEVICE, LifecyclePrebind, tString, orderDefault},
 
 	// ===== PRE OPTIONS (SRTO_R_PRE) =====
 	// These affect handshake, encryption, connection negotiation
-	{"fc", 0, SRTO_FC, LifecyclePre, tInteger32},
-	{"sender", 0, SRTO_SENDER, LifecyclePre, tBoolean},
-	{"tsbpdmode", 0, SRTO_TSBPDMODE, LifecyclePre, tBoolean},
-	{"latency", 0, SRTO_LATENCY, LifecyclePre, tInteger32},
-	{"rcvlatency", 0, SRTO_RCVLATENCY, LifecyclePre, tInteger32},
-	{"peerlatency", 0, SRTO_PEERLATENCY, LifecyclePre, tInteger32},
-	{"passphrase", 0, SRTO_PASSPHRASE, LifecyclePre, tString},
-	{"pbkeylen", 0, SRTO_PBKEYLEN, LifecyclePre, tInteger32},
-	{"tlpktdrop", 0, SRTO_TLPKTDROP, LifecyclePre, tBoolean},
-	{"nakreport", 0, SRTO_NAKREPORT, LifecyclePre, tBoolean},
-	{"conntimeo", 0, SRTO_CONNTIMEO, LifecyclePre, tInteger32},
-	{"streamid", 0, SRTO_STREAMID, LifecyclePre, tString},
-	{"payloadsize", 0, SRTO_PAYLOADSIZE, LifecyclePre, tInteger32},
-	{"messageapi", 0, SRTO_MESSAGEAPI, LifecyclePre, tBoolean},
-	{"minversion", 0, SRTO_MINVERSION, LifecyclePre, tInteger32},
-	{"enforcedencryption", 0, SRTO_ENFORCEDENCRYPTION, LifecyclePre, tBoolean},
-	{"peeridletimeo", 0, SRTO_PEERIDLETIMEO, LifecyclePre, tInteger32},
-	{"packetfilter", 0, SRTO_PACKETFILTER, LifecyclePre, tString},
-	{"congestion", 0, SRTO_CONGESTION, LifecyclePre, tString},
-	{"kmrefreshrate", 0, SRTO_KMREFRESHRATE, LifecyclePre, tInteger32},
-	{"kmpreannounce", 0, SRTO_KMPREANNOUNCE, LifecyclePre, tInteger32},
+	{"fc", 0, SRTO_FC, LifecyclePre, tInteger32, orderDefault},
+	{"sender", 0, SRTO_SENDER, LifecyclePre, tBoolean, orderDefault},
+	{"tsbpdmode", 0, SRTO_TSBPDMODE, LifecyclePre, tBoolean, orderDefault},
+	{"latency", 0, SRTO_LATENCY, LifecyclePre, tInteger32, orderEarly},
+	{"rcvlatency", 0, SRTO_RCVLATENCY, LifecyclePre, tInteger32, orderDefault},
+	{"peerlatency", 0, SRTO_PEERLATENCY, LifecyclePre, tInteger32, orderDefault},
+	{"passphrase", 0, SRTO_PASSPHRASE, LifecyclePre, tString, orderDefault},
+	{"pbkeylen", 0, SRTO_PBKEYLEN, LifecyclePre, tInteger32, orderDefault},
+	{"tlpktdrop", 0, SRTO_TLPKTDROP, LifecyclePre, tBoolean, orderDefault},
+	{"nakreport", 0, SRTO_NAKREPORT, LifecyclePre, tBoolean, orderDefault},
+	{"conntimeo", 0, SRTO_CONNTIMEO, LifecyclePre, tInteger32, orderDefault},
+	{"streamid", 0, SRTO_STREAMID, LifecyclePre, tString, orderDefault},
+	{"payloadsize", 0, SRTO_PAYLOADSIZE, LifecyclePre, tInteger32, orderDefault},
+	{"messageapi", 0, SRTO_MESSAGEAPI, LifecyclePre, tBoolean, orderDefault},
+	{"minversion", 0, SRTO_MINVERSION, LifecyclePre, tInteger32, orderDefault},
+	{"enforcedencryption", 0, SRTO_ENFORCEDENCRYPTION, LifecyclePre, tBoolean, orderDefault},
+	{"peeridletimeo", 0, SRTO_PEERIDLETIMEO, LifecyclePre, tInteger32, orderDefault},
+	{"packetfilter", 0, SRTO_PACKETFILTER, LifecyclePre, tString, orderDefault},
+	{"congestion", 0, SRTO_CONGESTION, LifecyclePre, tString, orderDefault},
+	{"kmrefreshrate", 0, SRTO_KMREFRESHRATE, LifecyclePre, tInteger32, orderDefault},
+	{"kmpreannounce", 0, SRTO_KMPREANNOUNCE, LifecyclePre, tInteger32, orderDefault},
+	{"groupconnect", 0, SRTO_GROUPCONNECT, LifecyclePre, tInteger32, orderDefault},
+	{"groupstabletimeo", 0, SRTO_GROUPMINSTABLETIMEO, LifecyclePre, tInteger32, orderDefault},
 
 	// ===== POST OPTIONS (no restriction flags) =====
 	// These can be adjusted anytime - bandwidth, loss handling, timeouts
-	{"maxbw", 0, SRTO_MAXBW, LifecyclePost, tInteger64},
-	{"inputbw", 0, SRTO_INPUTBW, LifecyclePost, tInteger64},
-	{"mininputbw", 0, SRTO_MININPUTBW, LifecyclePost, tInteger64},
-	{"oheadbw", 0, SRTO_OHEADBW, LifecyclePost, tInteger32},
-	{"snddropdelay", 0, SRTO_SNDDROPDELAY, LifecyclePost, tInteger32},
-	{"lossmaxttl", 0, SRTO_LOSSMAXTTL, LifecyclePost, tInteger32},
+	{"maxbw", 0, SRTO_MAXBW, LifecyclePost, tInteger64, orderDefault},
+	{"inputbw", 0, SRTO_INPUTBW, LifecyclePost, tInteger64, orderDefault},
+	{"mininputbw", 0, SRTO_MININPUTBW, LifecyclePost, tInteger64, orderDefault},
+	{"oheadbw", 0, SRTO_OHEADBW, LifecyclePost, tInteger32, orderDefault},
+	{"snddropdelay", 0, SRTO_SNDDROPDELAY, LifecyclePost, tInteger32, orderDefault},
+	{"lossmaxttl", 0, SRTO_LOSSMAXTTL, LifecyclePost, tInteger32, orderDefault},
+	{"sndtimeo", 0, SRTO_SNDTIMEO, LifecyclePost, tInteger32, orderDefault},
+	{"rcvtimeo", 0, SRTO_RCVTIMEO, LifecyclePost, tInteger32, orderDefault},
+}
+
+// socketOptionValidators holds additional value checks for options whose
+// accepted range is narrower than their data type
+var socketOptionValidators = map[string]func(val string) error{
+	"groupconnect": validateGroupConnect,
+	"congestion":   validateCongestionController,
+	"passphrase":   validatePassphrase,
+	"pbkeylen":     validatePBKeyLen,
+	"packetfilter": validatePacketFilter,
+	"streamid":     validateStreamID,
+}
+
+func validateGroupConnect(val string) error {
+	if val != "0" && val != "1" {
+		return fmt.Errorf("invalid groupconnect value: %s (must be 0 or 1)", val)
+	}
+	return nil
+}
+
+// Congestion controllers supported by SRT
+const (
+	CongestionLive = "live"
+	CongestionFile = "file"
+)
+
+func validateCongestionController(val string) error {
+	if val != CongestionLive && val != CongestionFile {
+		return fmt.Errorf("unknown congestion controller: %q (must be %q or %q)", val, CongestionLive, CongestionFile)
+	}
+	return nil
+}
+
+// Passphrase length limits imposed by SRT
+const (
+	minPassphraseLen = 10
+	maxPassphraseLen = 79
+)
+
+func validatePassphrase(val string) error {
+	// An empty passphrase disables encryption
+	if len(val) == 0 {
+		return nil
+	}
+	if len(val) < minPassphraseLen || len(val) > maxPassphraseLen {
+		return fmt.Errorf("invalid passphrase length %d (must be %d to %d characters)", len(val), minPassphraseLen, maxPassphraseLen)
+	}
+	return nil
+}
+
+// validateStreamID checks the limits SRT imposes on a streamid, which would
+// otherwise only surface as a generic error from srt_setsockopt
+func validateStreamID(val string) error {
+	if len(val) > maxStreamIDLen {
+		return fmt.Errorf("invalid streamid length %d (must be at most %d bytes)", len(val), maxStreamIDLen)
+	}
+	if !utf8.ValidString(val) {
+		return fmt.Errorf("invalid streamid: not valid UTF-8")
+	}
+	return nil
+}
+
+func validatePBKeyLen(val string) error {
+	switch val {
+	case "0", "16", "24", "32":
+		return nil
+	}
+	return fmt.Errorf("invalid pbkeylen value: %s (must be 0, 16, 24 or 32)", val)
+}
+
+// validateSocketOption runs the registered validator for the option, if any
+func validateSocketOption(name, val string) error {
+	if validate, ok := socketOptionValidators[name]; ok {
+		return validate(val)
+	}
+	return nil
 }
 
 func setSocketLingerOption(s C.int, li int32) error {
@@ -174,8 +284,40 @@ func getSocketLingerOption(s *SrtSocket) (int32, error) {
 	return lin.Linger, nil
 }
 
+// stringOptionPool recycles the buffers string option values are passed to
+// SRT in. A listener setting streamid or passphrase per connection would
+// otherwise pay for a C.CString/C.free pair on every call.
+var stringOptionPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 128)
+		return &b
+	},
+}
+
+// setStringOption sets a string option from a pooled, NUL-terminated copy of
+// val in Go memory. SRT copies the value before srt_setsockflag returns and
+// the buffer holds no Go pointers, so passing it to C is allowed by the cgo
+// pointer rules; the buffer returns to the pool only after the call, so it is
+// never reused while SRT reads it.
+func setStringOption(socket C.int, opt C.SRT_SOCKOPT, val string) C.int {
+	bp := stringOptionPool.Get().(*[]byte)
+	buf := append(append((*bp)[:0], val...), 0)
+	result := C.srt_setsockflag(socket, opt, unsafe.Pointer(&buf[0]), C.int32_t(len(val)))
+	// The value may be a passphrase, do not leave it in pooled memory
+	for i := range buf {
+		buf[i] = 0
+	}
+	*bp = buf[:0]
+	stringOptionPool.Put(bp)
+	return result
+}
+
 // setSocketOption sets a single socket option based on its data type
 func setSocketOption(socket C.int, optDef *socketOption, val string) error {
+	if err := validateSocketOption(optDef.name, val); err != nil {
+		return err
+	}
+
 	switch optDef.dataType {
 	case tInteger32:
 		v, err := strconv.Atoi(val)
@@ -199,9 +341,7 @@ func setSocketOption(socket C.int, optDef *socketOption, val string) error {
 		}
 
 	case tString:
-		sval := C.CString(val)
-		defer C.free(unsafe.Pointer(sval))
-		result := C.srt_setsockflag(socket, C.SRT_SOCKOPT(optDef.option), unsafe.Pointer(sval), C.int32_t(len(val)))
+		result := setStringOption(socket, C.SRT_SOCKOPT(optDef.option), val)
 		if result == -1 {
 			return srtGetAndClearError()
 		}
@@ -246,7 +386,8 @@ func setSocketOption(socket C.int, optDef *socketOption, val string) error {
 func setSocketOptionsForLifecycle(socket C.int, stage SrtOptionLifecycle, options map[string]string) error {
 	var errors []string
 
-	for name, val := range options {
+	for _, name := range optionApplyOrder(options) {
+		val := options[name]
 		// Find option definition in registry
 		var optDef *socketOption
 		for i := range SocketOptions {
@@ -281,6 +422,34 @@ func setSocketOptionsForLifecycle(socket C.int, stage SrtOptionLifecycle, option
 	return nil
 }
 
+// optionApplyOrder returns the option names in the order they must be set:
+// by the order of their SocketOptions entry, then by name, so that
+// configurations apply deterministically. Setting transtype makes SRT reset
+// the options it has defaults for (latency, messageapi, tsbpdmode,
+// payloadsize, ...), so it comes first; latency precedes rcvlatency and
+// peerlatency, which override the part of it they set. Unknown options sort
+// with the default order and are reported when they are set.
+func optionApplyOrder(options map[string]string) []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	order := func(name string) int {
+		if optDef := FindSocketOption(name); optDef != nil {
+			return optDef.order
+		}
+		return orderDefault
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, oj := order(names[i]), order(names[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
 // FindSocketOption looks up an option by name in the SocketOptions registry
 // Returns nil if the option is not found
 func FindSocketOption(name string) *socketOption {
@@ -297,7 +466,7 @@ func FindSocketOption(name string) *socketOption {
 func ValidateSocketOptionsForLifecycle(stage SrtOptionLifecycle, options map[string]string) error {
 	var errors []string
 
-	for name := range options {
+	for name, val := range options {
 		optDef := FindSocketOption(name)
 		if optDef == nil {
 			errors = append(errors, fmt.Sprintf("unknown option: %s", name))
@@ -307,6 +476,11 @@ func ValidateSocketOptionsForLifecycle(stage SrtOptionLifecycle, options map[str
 		if !optDef.CanSetAt(stage) {
 			errors = append(errors, fmt.Sprintf("option '%s' cannot be set at %s stage (requires %s)",
 				name, stage.String(), optDef.Lifecycle().String()))
+			continue
+		}
+
+		if err := validateSocketOption(name, val); err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", name, err))
 		}
 	}
 
@@ -334,3 +508,310 @@ func setSocketOptions(s C.int, binding int, options map[string]string) error {
 	}
 	return setSocketOptionsForLifecycle(s, stage, options)
 }
+
+// checkLifecycle returns an error if the socket has progressed past the given
+// lifecycle stage, i.e. if options of that stage can no longer be applied
+func (s SrtSocket) checkLifecycle(stage SrtOptionLifecycle) error {
+	state := C.srt_getsockstate(s.socket)
+	switch stage {
+	case LifecyclePrebind:
+		if state != C.SRTS_INIT {
+			return fmt.Errorf("option must be set before the socket is bound")
+		}
+	case LifecyclePre:
+		if s.inListenCallback && state != C.SRTS_CONNECTED && state < C.SRTS_BROKEN {
+			// The handshake waits for the listen callback to return
+			return nil
+		}
+		if state != C.SRTS_INIT && state != C.SRTS_OPENED {
+			return fmt.Errorf("option must be set before the socket connects or listens")
+		}
+	}
+	return nil
+}
+
+// setOption sets a registry option by name after checking that the socket is
+// still in a state where the option's lifecycle allows it
+func (s SrtSocket) setOption(name, val string) error {
+	optDef := FindSocketOption(name)
+	if optDef == nil {
+		return fmt.Errorf("unknown option: %s", name)
+	}
+	if err := s.checkLifecycle(optDef.Lifecycle()); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := s.checkPayloadFits(name, val); err != nil {
+		return err
+	}
+	if err := setSocketOption(s.socket, optDef, val); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// SetCongestionController selects the congestion controller (CongestionLive or
+// CongestionFile). Unknown names are rejected before reaching libsrt. This is a
+// PRE option, so it fails once the socket is connecting, connected or listening.
+func (s SrtSocket) SetCongestionController(name string) error {
+	return s.setOption("congestion", name)
+}
+
+// ErrPassphraseRotation is returned by SetPassphrase for a socket that is
+// connecting, connected or listening, see SetPassphrase.
+var ErrPassphraseRotation = errors.New("passphrase cannot be changed once the socket connects or listens")
+
+// SetPassphrase sets the encryption passphrase, validating its length (10 to 79
+// characters, or empty to disable encryption). SRT derives a key of the
+// configured pbkeylen from any valid passphrase, so the two need no check
+// against each other; pbkeylen itself is validated when it is set.
+//
+// Rotating the passphrase of a connected socket is not supported: SRT derives
+// the key-encrypting key from the passphrase during the handshake and fixes it
+// for the lifetime of the connection, so SetPassphrase returns
+// ErrPassphraseRotation once the socket is connecting, connected or listening,
+// except from a listen callback for the socket being accepted. What
+// kmrefreshrate and kmpreannounce control is the periodic refresh of the
+// session key, which is wrapped with that same passphrase and exchanged with
+// the peer in-band; this happens automatically and needs no call to
+// SetPassphrase. To change the passphrase, reconnect: callers set the new one
+// before Connect, listeners set it per connection from their listen callback.
+// With enforcedencryption enabled, a peer still using the old passphrase is
+// rejected at that reconnection.
+func (s SrtSocket) SetPassphrase(pass string) error {
+	if err := validatePassphrase(pass); err != nil {
+		return err
+	}
+	if err := s.checkLifecycle(LifecyclePre); err != nil {
+		return ErrPassphraseRotation
+	}
+	return s.setOption("passphrase", pass)
+}
+
+// BindToDevice pins the socket to the network interface iface, like
+// SO_BINDTODEVICE. This is a PREBIND option, so it must be called before
+// Listen or Connect. Only supported on Linux, and usually requires
+// CAP_NET_RAW.
+func (s SrtSocket) BindToDevice(iface string) error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("bindtodevice is not supported on %s", runtime.GOOS)
+	}
+	err := s.setOption("bindtodevice", iface)
+	if errors.Is(err, error(EInvOp)) {
+		return fmt.Errorf("bindtodevice is not supported by the linked SRT library: %w", err)
+	}
+	return err
+}
+
+// timeoutMs converts a timeout to the millisecond value expected by SRT,
+// where a negative duration means no timeout (-1)
+func timeoutMs(d time.Duration) string {
+	if d < 0 {
+		return "-1"
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
+// SetSendTimeout bounds how long a Write blocks in blocking mode (sndtimeo).
+// A negative duration blocks indefinitely. When the timeout expires, Write
+// returns an error whose Timeout() method reports true.
+// Non-blocking sockets should use SetWriteDeadline instead.
+func (s SrtSocket) SetSendTimeout(d time.Duration) error {
+	return s.setOption("sndtimeo", timeoutMs(d))
+}
+
+// SetReceiveTimeout bounds how long a Read blocks in blocking mode (rcvtimeo).
+// A negative duration blocks indefinitely. When the timeout expires, Read
+// returns an error whose Timeout() method reports true.
+// Non-blocking sockets should use SetReadDeadline instead.
+func (s SrtSocket) SetReceiveTimeout(d time.Duration) error {
+	return s.setOption("rcvtimeo", timeoutMs(d))
+}
+
+// MinConnectTimeout is the shortest connect timeout accepted by ConnectTimeout.
+// SRT takes conntimeo in whole milliseconds and rejects negative values; a
+// value of 0 would make every connection attempt fail immediately.
+const MinConnectTimeout = time.Millisecond
+
+// ConnectTimeout sets how long the next Connect waits for the handshake to
+// complete (conntimeo). Unlike the conntimeo option passed to NewSrtSocket, it
+// can be called before each connection attempt, e.g. to grow the timeout with
+// the backoff of a retry loop. It must be called before Connect.
+func (s SrtSocket) ConnectTimeout(d time.Duration) error {
+	if d < MinConnectTimeout {
+		return fmt.Errorf("connect timeout %s below minimum %s", d, MinConnectTimeout)
+	}
+	return s.setOption("conntimeo", timeoutMs(d))
+}
+
+// writeOnlySocketOptions cannot be read back with srt_getsockopt
+var writeOnlySocketOptions = map[string]bool{
+	"passphrase": true,
+	"transtype":  true,
+	"sender":     true,
+}
+
+// getSocketOption reads a registry option as an int, int64, bool or string,
+// following its data type
+func (s SrtSocket) getSocketOption(optDef *socketOption) (interface{}, error) {
+	switch optDef.dataType {
+	case tInteger32:
+		return s.GetSockOptInt(optDef.option)
+	case tInteger64:
+		return s.GetSockOptInt64(optDef.option)
+	case tString:
+		return s.GetSockOptString(optDef.option)
+	case tBoolean:
+		return s.GetSockOptBool(optDef.option)
+	}
+	return nil, fmt.Errorf("unsupported data type %d", optDef.dataType)
+}
+
+// exportSocketOption reads a registry option and formats it the way it would
+// be passed in the options map
+func (s SrtSocket) exportSocketOption(optDef *socketOption) (string, error) {
+	v, err := s.getSocketOption(optDef)
+	if err != nil {
+		return "", err
+	}
+	return formatOptionValue(optDef, v)
+}
+
+// ExportOptions returns the current value of every option in the
+// SocketOptions registry, keyed by option name and formatted as in the
+// options map, for diagnostics. Called on a connected socket it reports the
+// negotiated values. Write-only options (passphrase, transtype, sender) are
+// left out; options that cannot be read are reported as "<error: ...>".
+func (s SrtSocket) ExportOptions() (map[string]string, error) {
+	if s.socket == SRT_INVALID_SOCK {
+		return nil, fmt.Errorf("invalid socket")
+	}
+	opts := make(map[string]string, len(SocketOptions))
+	for i := range SocketOptions {
+		optDef := &SocketOptions[i]
+		if writeOnlySocketOptions[optDef.name] {
+			continue
+		}
+		val, err := s.exportSocketOption(optDef)
+		if err != nil {
+			val = fmt.Sprintf("<error: %v>", err)
+		}
+		opts[optDef.name] = val
+	}
+	return opts, nil
+}
+
+// OptionTypeError is returned by SetOption when the Go type of a value does
+// not match the data type of the option
+type OptionTypeError struct {
+	Option   string      // option name
+	Expected string      // accepted Go types
+	Value    interface{} // rejected value
+}
+
+func (e *OptionTypeError) Error() string {
+	return fmt.Sprintf("option %s expects %s, got %T", e.Option, e.Expected, e.Value)
+}
+
+// optionTypeNames describes the Go values accepted for each option data type
+var optionTypeNames = map[int]string{
+	tInteger32: "an integer fitting in 32 bits",
+	tInteger64: "an integer",
+	tString:    "a string",
+	tBoolean:   "a bool",
+	tTransType: "a string (live or file)",
+}
+
+// integerValue converts the Go integer types to int64, rejecting unsigned
+// values that do not fit
+func integerValue(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), uint64(v) <= math.MaxInt64
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	}
+	return 0, false
+}
+
+// formatOptionValue converts a typed value to the string form of the options
+// map, checking it against the data type of the option
+func formatOptionValue(optDef *socketOption, value interface{}) (string, error) {
+	typeErr := &OptionTypeError{Option: optDef.name, Expected: optionTypeNames[optDef.dataType], Value: value}
+	switch optDef.dataType {
+	case tInteger32:
+		v, ok := integerValue(value)
+		if !ok || v < math.MinInt32 || v > math.MaxInt32 {
+			return "", typeErr
+		}
+		return strconv.FormatInt(v, 10), nil
+	case tInteger64:
+		v, ok := integerValue(value)
+		if !ok {
+			return "", typeErr
+		}
+		return strconv.FormatInt(v, 10), nil
+	case tBoolean:
+		v, ok := value.(bool)
+		if !ok {
+			return "", typeErr
+		}
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case tString, tTransType:
+		v, ok := value.(string)
+		if !ok {
+			return "", typeErr
+		}
+		return v, nil
+	}
+	return "", fmt.Errorf("unsupported data type %d", optDef.dataType)
+}
+
+// SetOption sets an option of the SocketOptions registry from a typed value:
+// an integer type for integer options, bool for boolean ones and string for
+// string options and transtype. Values of another Go type are rejected with an
+// OptionTypeError; durations in particular must be converted to the unit of
+// the option. The lifecycle and value checks of the options map apply.
+func (s SrtSocket) SetOption(name string, value interface{}) error {
+	optDef := FindSocketOption(name)
+	if optDef == nil {
+		return fmt.Errorf("unknown option: %s", name)
+	}
+	val, err := formatOptionValue(optDef, value)
+	if err != nil {
+		return err
+	}
+	return s.setOption(name, val)
+}
+
+// GetOption returns the current value of an option of the SocketOptions
+// registry as an int, int64, bool or string, following its data type.
+// Write-only options (passphrase, transtype, sender) cannot be read.
+func (s SrtSocket) GetOption(name string) (interface{}, error) {
+	optDef := FindSocketOption(name)
+	if optDef == nil {
+		return nil, fmt.Errorf("unknown option: %s", name)
+	}
+	if writeOnlySocketOptions[name] {
+		return nil, fmt.Errorf("option %s is write-only", name)
+	}
+	return s.getSocketOption(optDef)
+}
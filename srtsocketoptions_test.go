@@ -0,0 +1,290 @@
+package srtgo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateGroupConnect(t *testing.T) {
+	for _, val := range []string{"0", "1"} {
+		err := ValidateSocketOptionsForLifecycle(LifecyclePre, map[string]string{"groupconnect": val})
+		if err != nil {
+			t.Errorf("groupconnect=%s should be valid, got %v", val, err)
+		}
+	}
+
+	for _, val := range []string{"2", "-1", "true"} {
+		err := ValidateSocketOptionsForLifecycle(LifecyclePre, map[string]string{"groupconnect": val})
+		if err == nil {
+			t.Errorf("groupconnect=%s should be rejected", val)
+		}
+	}
+}
+
+func TestValidateStreamID(t *testing.T) {
+	valid := []string{"", "#!::r=live/feed,m=publish", "Kanał-1", strings.Repeat("a", maxStreamIDLen)}
+	for _, val := range valid {
+		if err := ValidateSocketOptionsForLifecycle(LifecyclePre, map[string]string{"streamid": val}); err != nil {
+			t.Errorf("streamid of %d bytes should be valid, got %v", len(val), err)
+		}
+	}
+
+	invalid := []string{strings.Repeat("a", maxStreamIDLen+1), "bad\xff\xfe"}
+	for _, val := range invalid {
+		if err := ValidateSocketOptionsForLifecycle(LifecyclePre, map[string]string{"streamid": val}); err == nil {
+			t.Errorf("streamid %q should be rejected", val)
+		}
+	}
+}
+
+func TestGroupStableTimeoIsPre(t *testing.T) {
+	opt := FindSocketOption("groupstabletimeo")
+	if opt == nil {
+		t.Fatal("groupstabletimeo missing from registry")
+	}
+	if opt.Lifecycle() != LifecyclePre {
+		t.Errorf("expected pre lifecycle, got %s", opt.Lifecycle())
+	}
+}
+
+func TestSetCongestionController(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+
+	if err := a.SetCongestionController(CongestionFile); err != nil {
+		t.Error(err)
+	}
+	if err := a.SetCongestionController("lve"); err == nil {
+		t.Error("expected error for unknown congestion controller")
+	}
+}
+
+func TestSetPassphrase(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+
+	if err := a.SetPassphrase("0123456789"); err != nil {
+		t.Error(err)
+	}
+	if err := a.SetPassphrase("short"); err == nil {
+		t.Error("expected error for short passphrase")
+	}
+	if err := a.SetPassphrase(strings.Repeat("x", 80)); err == nil {
+		t.Error("expected error for long passphrase")
+	}
+}
+
+func TestSetPassphraseConnected(t *testing.T) {
+	options := map[string]string{"passphrase": "0123456789"}
+	caller, remote := connectedPair(t, options)
+	defer caller.Close()
+	defer remote.Close()
+
+	for _, s := range []*SrtSocket{caller, remote} {
+		if err := s.SetPassphrase("9876543210"); !errors.Is(err, ErrPassphraseRotation) {
+			t.Errorf("expected ErrPassphraseRotation, got %v", err)
+		}
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+
+	if err := a.ConnectTimeout(500 * time.Millisecond); err != nil {
+		t.Error(err)
+	}
+	if v, err := a.GetSockOptInt(SRTO_CONNTIMEO); err != nil || v != 500 {
+		t.Errorf("expected conntimeo 500, got %d (%v)", v, err)
+	}
+	if err := a.ConnectTimeout(0); err == nil {
+		t.Error("expected error for connect timeout below minimum")
+	}
+}
+
+func TestExportOptions(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live", "latency": "200"})
+	defer caller.Close()
+	defer remote.Close()
+
+	opts, err := caller.ExportOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts["latency"] != "200" {
+		t.Errorf("expected latency 200, got %q", opts["latency"])
+	}
+	if _, ok := opts["passphrase"]; ok {
+		t.Error("write-only passphrase should not be exported")
+	}
+	for name, val := range opts {
+		if FindSocketOption(name) == nil {
+			t.Errorf("exported unknown option %q=%q", name, val)
+		}
+	}
+}
+
+func TestExportOptionsLongStreamID(t *testing.T) {
+	InitSRT()
+	streamid := strings.Repeat("s", 500)
+	a := NewSrtSocket("localhost", 8090, map[string]string{"streamid": streamid})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+
+	opts, err := a.ExportOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts["streamid"] != streamid {
+		t.Errorf("expected a streamid of %d bytes, got %d", len(streamid), len(opts["streamid"]))
+	}
+}
+
+func TestTransTypeKeepsLinger(t *testing.T) {
+	InitSRT()
+	// transtype resets linger, to 180s in file mode
+	a := NewSrtSocket("localhost", 8090, map[string]string{"transtype": "file", "linger": "5"})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+	linger, err := getSocketLingerOption(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linger != 5 {
+		t.Errorf("linger clobbered by transtype: expected 5, got %d", linger)
+	}
+}
+
+func TestSetOptionTyped(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+
+	if err := a.SetOption("latency", 250); err != nil {
+		t.Error(err)
+	}
+	if err := a.SetOption("maxbw", int64(1000000)); err != nil {
+		t.Error(err)
+	}
+	if err := a.SetOption("messageapi", true); err != nil {
+		t.Error(err)
+	}
+	if err := a.SetOption("streamid", "#!::r=live"); err != nil {
+		t.Error(err)
+	}
+
+	if v, err := a.GetOption("latency"); err != nil || v != 250 {
+		t.Errorf("expected latency 250, got %v (%v)", v, err)
+	}
+	if v, err := a.GetOption("maxbw"); err != nil || v != int64(1000000) {
+		t.Errorf("expected maxbw 1000000, got %v (%v)", v, err)
+	}
+	if v, err := a.GetOption("messageapi"); err != nil || v != true {
+		t.Errorf("expected messageapi true, got %v (%v)", v, err)
+	}
+	if v, err := a.GetOption("streamid"); err != nil || v != "#!::r=live" {
+		t.Errorf("unexpected streamid %v (%v)", v, err)
+	}
+	long := strings.Repeat("s", maxStreamIDLen)
+	if err := a.SetOption("streamid", long); err != nil {
+		t.Error(err)
+	}
+	if v, err := a.GetOption("streamid"); err != nil || v != long {
+		t.Errorf("expected a streamid of %d bytes, got %v (%v)", len(long), v, err)
+	}
+
+	for name, value := range map[string]interface{}{
+		"latency":    "250",
+		"messageapi": 1,
+		"streamid":   42,
+		"conntimeo":  time.Second,
+		"rcvbuf":     int64(1) << 40,
+	} {
+		var typeErr *OptionTypeError
+		if err := a.SetOption(name, value); !errors.As(err, &typeErr) {
+			t.Errorf("SetOption(%s, %T) should fail with OptionTypeError, got %v", name, value, err)
+		}
+	}
+	if _, err := a.GetOption("passphrase"); err == nil {
+		t.Error("expected error reading a write-only option")
+	}
+}
+
+func TestValidatePayloadSize(t *testing.T) {
+	tests := []struct {
+		options map[string]string
+		ipv6    bool
+		valid   bool
+	}{
+		{map[string]string{}, false, true},
+		{map[string]string{"payloadsize": "1456"}, false, true},
+		{map[string]string{"payloadsize": "1457"}, false, false},
+		{map[string]string{"payloadsize": "1316", "mss": "1360"}, false, true},
+		{map[string]string{"payloadsize": "1456", "mss": "1360"}, false, false},
+		{map[string]string{"payloadsize": "big"}, false, false},
+		{map[string]string{"payloadsize": "1436"}, true, true},
+		{map[string]string{"payloadsize": "1437"}, true, false},
+		{map[string]string{"payloadsize": "1316", "mss": "1380"}, true, true},
+		{map[string]string{"payloadsize": "1316", "mss": "1360"}, true, false},
+	}
+	for _, tt := range tests {
+		if err := ValidatePayloadSize(tt.options, tt.ipv6); (err == nil) != tt.valid {
+			t.Errorf("%v (ipv6=%t): expected valid=%t, got %v", tt.options, tt.ipv6, tt.valid, err)
+		}
+	}
+
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{"mss": "1360"})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+	if err := a.SetOption("payloadsize", 1456); err == nil {
+		t.Error("expected a payloadsize exceeding the mss to be rejected")
+	}
+	if err := a.SetOption("payloadsize", 1316); err != nil {
+		t.Errorf("expected payloadsize 1316 to fit mss 1360, got %v", err)
+	}
+}
+
+// BenchmarkSetStringOption measures setting a string option through the
+// pooled buffers; run it against the revision before pooling, which copied the
+// value with C.CString, and compare the results with benchstat
+func BenchmarkSetStringOption(b *testing.B) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		b.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+	optDef := FindSocketOption("streamid")
+	streamid := "#!::u=admin,r=live/cam1,m=publish"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := setSocketOption(a.socket, optDef, streamid); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
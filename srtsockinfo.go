@@ -0,0 +1,508 @@
+package srtgo
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// checkConnected returns an error unless the handshake has completed
+func (s SrtSocket) checkConnected() error {
+	if C.srt_getsockstate(s.socket) != C.SRTS_CONNECTED {
+		return fmt.Errorf("socket is not connected")
+	}
+	return nil
+}
+
+// PeerVersion returns the SRT version of the peer, encoded as 0xXXYYZZ.
+// Use DecodeVersion to split it into major, minor and patch.
+// Only available once the socket is connected.
+func (s SrtSocket) PeerVersion() (uint32, error) {
+	if err := s.checkConnected(); err != nil {
+		return 0, err
+	}
+	v, err := s.GetSockOptInt(SRTO_PEERVERSION)
+	return uint32(v), err
+}
+
+// NegotiatedLatency returns the receiver and peer latency in ms agreed during
+// the handshake, which may be higher than the values requested locally.
+// Only available once the socket is connected.
+func (s SrtSocket) NegotiatedLatency() (rcvMs, peerMs int, err error) {
+	if err = s.checkConnected(); err != nil {
+		return 0, 0, err
+	}
+	if rcvMs, err = s.GetSockOptInt(SRTO_RCVLATENCY); err != nil {
+		return 0, 0, err
+	}
+	if peerMs, err = s.GetSockOptInt(SRTO_PEERLATENCY); err != nil {
+		return 0, 0, err
+	}
+	return rcvMs, peerMs, nil
+}
+
+// EffectiveReceiveLatency returns the latency this socket applies when
+// receiving, as agreed during the handshake: the larger of the local
+// rcvlatency and the peerlatency requested by the sender.
+// Only available once the socket is connected, as the value is not final before.
+func (s SrtSocket) EffectiveReceiveLatency() (time.Duration, error) {
+	rcvMs, _, err := s.NegotiatedLatency()
+	return time.Duration(rcvMs) * time.Millisecond, err
+}
+
+// EffectivePeerLatency returns the latency the peer applies when receiving
+// from this socket, as agreed during the handshake.
+// Only available once the socket is connected, as the value is not final before.
+func (s SrtSocket) EffectivePeerLatency() (time.Duration, error) {
+	_, peerMs, err := s.NegotiatedLatency()
+	return time.Duration(peerMs) * time.Millisecond, err
+}
+
+// Per-packet header overhead subtracted from the MSS to obtain the maximum
+// payload: IP (20 for IPv4, 40 for IPv6) + UDP (8) + SRT (16) headers
+const (
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+	udpHeaderSize  = 8
+	srtHeaderSize  = 16
+)
+
+// packetOverhead returns the size of the headers of a packet, 44 bytes over
+// IPv4 and 64 bytes over IPv6
+func packetOverhead(ipv6 bool) int {
+	if ipv6 {
+		return ipv6HeaderSize + udpHeaderSize + srtHeaderSize
+	}
+	return ipv4HeaderSize + udpHeaderSize + srtHeaderSize
+}
+
+// isIPv6 reports whether the socket sends its packets over IPv6: the family of
+// the peer address once connected, otherwise of the host the socket was
+// created with. IPv4-mapped peers of a dual-stack socket are reached over
+// IPv4, as are host names that are not resolved yet.
+func (s SrtSocket) isIPv6() bool {
+	if addr, err := s.PeerAddr(); err == nil {
+		return addr.IP.To4() == nil
+	}
+	ip := net.ParseIP(s.host)
+	return ip != nil && ip.To4() == nil
+}
+
+// defaultMSS is the default of the mss option, the Ethernet MTU
+const defaultMSS = 1500
+
+// checkPayloadSize returns an error if packets of payloadSize bytes do not fit
+// into the MTU given by mss: the largest payload is mss minus the packet
+// headers (44 bytes over IPv4, 64 over IPv6), e.g. 1456 for the default mss
+// of 1500 over IPv4, 1436 over IPv6, and 1316 for an mss of 1360 on an IPv4
+// VPN path. A larger payload makes IP fragment every packet, which SRT does
+// not report but which degrades live streams on lossy links.
+func checkPayloadSize(payloadSize, mss int, ipv6 bool) error {
+	overhead := packetOverhead(ipv6)
+	if max := mss - overhead; payloadSize > max {
+		family := "IPv4"
+		if ipv6 {
+			family = "IPv6"
+		}
+		return fmt.Errorf("payloadsize %d does not fit mss %d: at most %d bytes are left after %d bytes of %s, UDP and SRT headers",
+			payloadSize, mss, max, overhead, family)
+	}
+	return nil
+}
+
+// ValidatePayloadSize checks that the payloadsize of an options map fits into
+// the MTU set by its mss option, or the default of 1500 bytes, for packets
+// sent over IPv6 if ipv6 is set and over IPv4 otherwise, see checkPayloadSize.
+// SetOption performs the same check against the mss and the address family
+// of the socket when payloadsize or mss are changed.
+func ValidatePayloadSize(options map[string]string, ipv6 bool) error {
+	val, ok := options["payloadsize"]
+	if !ok {
+		return nil
+	}
+	payloadSize, err := strconv.Atoi(val)
+	if err != nil {
+		return fmt.Errorf("invalid payloadsize value: %s", val)
+	}
+	mss := defaultMSS
+	if val, ok := options["mss"]; ok {
+		if mss, err = strconv.Atoi(val); err != nil {
+			return fmt.Errorf("invalid mss value: %s", val)
+		}
+	}
+	return checkPayloadSize(payloadSize, mss, ipv6)
+}
+
+// checkPayloadFits verifies that setting payloadsize or mss to val keeps the
+// payload size of the socket within its MTU
+func (s SrtSocket) checkPayloadFits(name, val string) error {
+	if name != "payloadsize" && name != "mss" {
+		return nil
+	}
+	v, err := strconv.Atoi(val)
+	if err != nil {
+		// Reported by setSocketOption
+		return nil
+	}
+	payloadSize, mss := v, v
+	if name == "payloadsize" {
+		mss, err = s.GetSockOptInt(SRTO_MSS)
+	} else {
+		payloadSize, err = s.GetSockOptInt(SRTO_PAYLOADSIZE)
+	}
+	if err != nil || payloadSize <= 0 {
+		return nil
+	}
+	return checkPayloadSize(payloadSize, mss, s.isIPv6())
+}
+
+// MaxPayloadSize returns the largest payload a single packet can carry on this
+// connection. In live mode this is the negotiated payloadsize, which bounds the
+// size of a single Write; when no payload size is set (file mode) it is derived
+// from the MSS minus the headers of a packet, which are 20 bytes larger over
+// IPv6 than over IPv4. Only available once the socket is connected, as the
+// value is not final before the handshake completes.
+func (s SrtSocket) MaxPayloadSize() (int, error) {
+	if err := s.checkConnected(); err != nil {
+		return 0, err
+	}
+	payloadSize, err := s.GetSockOptInt(SRTO_PAYLOADSIZE)
+	if err != nil {
+		return 0, err
+	}
+	if payloadSize > 0 {
+		return payloadSize, nil
+	}
+	mss, err := s.GetSockOptInt(SRTO_MSS)
+	if err != nil {
+		return 0, err
+	}
+	return mss - packetOverhead(s.isIPv6()), nil
+}
+
+// SendBufferPackets returns the number of packets held in the send buffer,
+// i.e. packets handed to Write that the peer has not acknowledged yet.
+// A connected but idle socket reports 0.
+func (s SrtSocket) SendBufferPackets() (int, error) {
+	return s.GetSockOptInt(SRTO_SNDDATA)
+}
+
+// ReceiveBufferPackets returns the number of packets held in the receive
+// buffer that have not been read by the application yet.
+// A connected but idle socket reports 0.
+func (s SrtSocket) ReceiveBufferPackets() (int, error) {
+	return s.GetSockOptInt(SRTO_RCVDATA)
+}
+
+// SendBufferBytes returns the number of payload bytes held in the send
+// buffer, i.e. bytes handed to Write that the peer has not acknowledged yet.
+// A connected but idle socket reports 0.
+func (s SrtSocket) SendBufferBytes() (int, error) {
+	var blocks, bytes C.size_t
+	if C.srt_getsndbuffer(s.socket, &blocks, &bytes) == SRT_ERROR {
+		return 0, fmt.Errorf("Error getting send buffer, %w", srtGetAndClearErrorThreadSafe())
+	}
+	return int(bytes), nil
+}
+
+// ReceiveBufferBytes returns the number of payload bytes held in the receive
+// buffer that have not been read by the application yet. Unlike Stats, this
+// does not reset the interval counters.
+// A connected but idle socket reports 0.
+func (s SrtSocket) ReceiveBufferBytes() (int, error) {
+	var stats C.SRT_TRACEBSTATS
+	if C.srt_bstats(s.socket, &stats, 0) == SRT_ERROR {
+		return 0, fmt.Errorf("Error getting stats, %w", srtGetAndClearErrorThreadSafe())
+	}
+	return int(stats.byteRcvBuf), nil
+}
+
+// SrtKmState is the state of the key material exchange of an encrypted socket
+type SrtKmState int
+
+const (
+	// SrtKmStateUnsecured - no encryption is configured
+	SrtKmStateUnsecured SrtKmState = SrtKmState(C.SRT_KM_S_UNSECURED)
+	// SrtKmStateSecuring - the key exchange is in progress
+	SrtKmStateSecuring SrtKmState = SrtKmState(C.SRT_KM_S_SECURING)
+	// SrtKmStateSecured - the key exchange succeeded, data is encrypted
+	SrtKmStateSecured SrtKmState = SrtKmState(C.SRT_KM_S_SECURED)
+	// SrtKmStateNoSecret - the peer uses encryption but no passphrase is set locally
+	SrtKmStateNoSecret SrtKmState = SrtKmState(C.SRT_KM_S_NOSECRET)
+	// SrtKmStateBadSecret - the passphrases on both sides do not match
+	SrtKmStateBadSecret SrtKmState = SrtKmState(C.SRT_KM_S_BADSECRET)
+)
+
+// String returns human-readable key material state name
+func (k SrtKmState) String() string {
+	switch k {
+	case SrtKmStateUnsecured:
+		return "unsecured"
+	case SrtKmStateSecuring:
+		return "securing"
+	case SrtKmStateSecured:
+		return "secured"
+	case SrtKmStateNoSecret:
+		return "nosecret"
+	case SrtKmStateBadSecret:
+		return "badsecret"
+	default:
+		return "unknown"
+	}
+}
+
+// EncryptionState returns the key material state of the sending and the
+// receiving direction. Both must be SrtKmStateSecured for the stream to be
+// encrypted end-to-end; they can differ, e.g. when only one side has a
+// passphrase configured.
+func (s SrtSocket) EncryptionState() (send, receive SrtKmState, err error) {
+	snd, err := s.GetSockOptInt(SRTO_SNDKMSTATE)
+	if err != nil {
+		return 0, 0, err
+	}
+	rcv, err := s.GetSockOptInt(SRTO_RCVKMSTATE)
+	if err != nil {
+		return 0, 0, err
+	}
+	return SrtKmState(snd), SrtKmState(rcv), nil
+}
+
+// KmStateEvent reports a change of the key material state of a socket
+type KmStateEvent struct {
+	Time                  time.Time
+	Send, Receive         SrtKmState // new state of each direction
+	PrevSend, PrevReceive SrtKmState // state before the change
+}
+
+// Defaults of EncryptionStateEvents
+const (
+	defaultKmPollInterval = time.Second
+	kmEventBuffer         = 16
+)
+
+// EncryptionStateEvents polls the key material state of both directions every
+// interval (1s if not positive) and sends an event on the returned channel
+// whenever it changes, e.g. securing to secured once the key exchange
+// completes, or secured to badsecret. The state at the first poll is the
+// baseline and is not reported. Polling costs two srt_getsockopt calls per
+// interval, which read local state only and cause no network traffic. If the
+// channel is not drained, events are dropped rather than blocking the poller.
+// The channel is closed once the socket is closed or the connection breaks.
+//
+// Detecting periodic key refreshes (kmrefreshrate/kmpreannounce) is not
+// supported. A refresh keeps the state at secured, and SRT exposes neither
+// the active key index nor a refresh count through its options or
+// statistics, so refreshes produce no event; only the initial exchange and
+// failures do.
+func (s SrtSocket) EncryptionStateEvents(interval time.Duration) <-chan KmStateEvent {
+	return s.encryptionStateEvents(interval, nil)
+}
+
+// encryptionStateEvents is EncryptionStateEvents that also ends once stop is
+// closed; a nil stop never does
+func (s SrtSocket) encryptionStateEvents(interval time.Duration, stop <-chan struct{}) <-chan KmStateEvent {
+	if interval <= 0 {
+		interval = defaultKmPollInterval
+	}
+	events := make(chan KmStateEvent, kmEventBuffer)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		send, receive, err := s.EncryptionState()
+		for ; ; <-ticker.C {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			switch C.srt_getsockstate(s.socket) {
+			case C.SRTS_BROKEN, C.SRTS_CLOSING, C.SRTS_CLOSED, C.SRTS_NONEXIST:
+				return
+			}
+			if err != nil {
+				send, receive, err = s.EncryptionState()
+				continue
+			}
+			newSend, newReceive, pollErr := s.EncryptionState()
+			if pollErr != nil || (newSend == send && newReceive == receive) {
+				continue
+			}
+			ev := KmStateEvent{
+				Time:        time.Now(),
+				Send:        newSend,
+				Receive:     newReceive,
+				PrevSend:    send,
+				PrevReceive: receive,
+			}
+			send, receive = newSend, newReceive
+			select {
+			case events <- ev:
+			default:
+			}
+		}
+	}()
+	return events
+}
+
+// MessageAPI reports whether the socket uses message mode (messageapi), where
+// Read and Write preserve message boundaries, rather than stream mode, where
+// the data is a byte stream. Live mode always uses message mode; file mode
+// uses stream mode unless messageapi is enabled.
+func (s SrtSocket) MessageAPI() (bool, error) {
+	return s.GetSockOptBool(SRTO_MESSAGEAPI)
+}
+
+// OutOfOrderDelivery reports whether messages sent with InOrder false (see
+// WriteMsg) may be delivered ahead of earlier ones: this requires message mode
+// without TSBPD, i.e. file mode with messageapi. Live mode always delivers in
+// source time order.
+func (s SrtSocket) OutOfOrderDelivery() (bool, error) {
+	messageAPI, err := s.MessageAPI()
+	if err != nil || !messageAPI {
+		return false, err
+	}
+	tsbpd, err := s.GetSockOptBool(SRTO_TSBPDMODE)
+	if err != nil {
+		return false, err
+	}
+	return !tsbpd, nil
+}
+
+// ReorderTolerance returns the current reorder tolerance in packets
+// (lossmaxttl): how many packets received after a gap SRT waits before
+// reporting the missing ones as lost. 0 disables the tolerance.
+func (s SrtSocket) ReorderTolerance() (int, error) {
+	return s.GetSockOptInt(SRTO_LOSSMAXTTL)
+}
+
+// SetReorderTolerance sets the reorder tolerance in packets (lossmaxttl).
+// This is a POST option, so it can be adjusted on a connected socket, e.g. to
+// follow ObservedReorderDistance.
+func (s SrtSocket) SetReorderTolerance(packets int) error {
+	if packets < 0 {
+		return fmt.Errorf("invalid reorder tolerance %d (must not be negative)", packets)
+	}
+	return s.setOption("lossmaxttl", strconv.Itoa(packets))
+}
+
+// ObservedReorderDistance returns the largest distance, in packets, between
+// out of order packets received on this connection so far. Setting the reorder
+// tolerance to at least this value avoids spurious retransmission requests on
+// paths that reorder packets. Unlike Stats, this does not reset the interval
+// counters.
+func (s SrtSocket) ObservedReorderDistance() (int, error) {
+	stats, err := s.stats(false)
+	if err != nil {
+		return 0, err
+	}
+	return stats.PktReorderDistance, nil
+}
+
+// SenderDroppedPackets returns the number of packets the sender dropped so far
+// because they were too late to be delivered (pktSndDropTotal). Unlike Stats,
+// this does not reset the interval counters.
+//
+// In live mode with tlpktdrop enabled, the sender drops a packet that is still
+// unacknowledged when it is older than the peer latency plus snddropdelay, see
+// SetSendDropDelay. A larger delay keeps packets longer in the hope that a
+// retransmission still arrives in time, at the cost of more buffered data; a
+// smaller one favors latency over completeness. A count that grows quickly
+// suggests the latency or the drop delay is too small for the link.
+func (s SrtSocket) SenderDroppedPackets() (int, error) {
+	stats, err := s.stats(false)
+	if err != nil {
+		return 0, err
+	}
+	return stats.PktSndDropTotal, nil
+}
+
+// CongestionWindow returns the current congestion window of the sender, in
+// packets (pktCongestionWindow): the number of unacknowledged packets the
+// congestion control allows in flight. Live mode does not use it to limit the
+// sender; in file mode it grows until loss is detected.
+func (s SrtSocket) CongestionWindow() (int, error) {
+	stats, err := s.stats(false)
+	if err != nil {
+		return 0, err
+	}
+	return stats.PktCongestionWindow, nil
+}
+
+// FlowWindow returns the current flow window of the sender, in packets
+// (pktFlowWindow): the free space the peer last reported in its receive
+// buffer, capped by the fc option of the peer. The sender never has more
+// than min(FlowWindow, CongestionWindow) packets in flight, so the throughput
+// is bounded by that window times the payload size per RTT. On a long-fat
+// link, a flow window that stays at fc while the throughput falls short of
+// the link rate means fc (or rcvbuf, which fc must not exceed) is too small
+// for the bandwidth-delay product: fc should be at least rate * RTT / (8 *
+// payload size) packets, for a rate in bits per second.
+func (s SrtSocket) FlowWindow() (int, error) {
+	stats, err := s.stats(false)
+	if err != nil {
+		return 0, err
+	}
+	return stats.PktFlowWindow, nil
+}
+
+// SetSendDropDelay sets the extra time, on top of the peer latency, that the
+// sender keeps unacknowledged packets before dropping them as too late
+// (snddropdelay). A negative d disables dropping by the sender. This is a POST
+// option, so it can be adjusted on a connected socket, e.g. based on
+// SenderDroppedPackets.
+func (s SrtSocket) SetSendDropDelay(d time.Duration) error {
+	ms := -1
+	if d >= 0 {
+		ms = int(d / time.Millisecond)
+	}
+	return s.setOption("snddropdelay", strconv.Itoa(ms))
+}
+
+// UDPBufferSizes returns the UDP send and receive buffer sizes in bytes
+// configured for the socket (udp_sndbuf and udp_rcvbuf)
+func (s SrtSocket) UDPBufferSizes() (snd, rcv int, err error) {
+	if snd, err = s.GetSockOptInt(SRTO_UDP_SNDBUF); err != nil {
+		return 0, 0, err
+	}
+	if rcv, err = s.GetSockOptInt(SRTO_UDP_RCVBUF); err != nil {
+		return 0, 0, err
+	}
+	return snd, rcv, nil
+}
+
+// UDPBufferCheck compares a configured UDP buffer size with the size the
+// operating system grants
+type UDPBufferCheck struct {
+	Requested int  // size configured with udp_sndbuf/udp_rcvbuf, in bytes
+	Effective int  // size granted by the operating system, in bytes
+	Capped    bool // the operating system granted less than requested
+}
+
+// CheckUDPBufferSizes reports whether the operating system honors the UDP
+// buffer sizes configured for the socket. SRT reports the configured values
+// only, and the kernel silently caps larger requests: on Linux to the
+// net.core.wmem_max and net.core.rmem_max sysctls (raise them with e.g.
+// sysctl -w net.core.rmem_max=26214400). A capped receive buffer is a common
+// cause of packet loss at high bitrates. The granted sizes are determined by
+// requesting the same sizes on a scratch UDP socket.
+func (s SrtSocket) CheckUDPBufferSizes() (snd, rcv UDPBufferCheck, err error) {
+	if snd.Requested, rcv.Requested, err = s.UDPBufferSizes(); err != nil {
+		return snd, rcv, err
+	}
+	if snd.Effective, err = probeUDPBufferSize(snd.Requested, true); err != nil {
+		return snd, rcv, err
+	}
+	if rcv.Effective, err = probeUDPBufferSize(rcv.Requested, false); err != nil {
+		return snd, rcv, err
+	}
+	snd.Capped = snd.Effective < snd.Requested
+	rcv.Capped = rcv.Effective < rcv.Requested
+	return snd, rcv, nil
+}
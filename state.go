@@ -0,0 +1,54 @@
+package srtgo
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// socketState holds the Go-side state of an SRT socket that outlives a single
+// call. Like pd and closed it is referenced by pointer from SrtSocket, so that
+// every copy of a handle shares it, and it is released once by Close.
+type socketState struct {
+	mu          sync.Mutex
+	userData    interface{}
+	writeClosed bool
+	// peerAddr is the peer address watch, peerAddrWatched is set while there
+	// is one, so that the read path can skip locking when there is none
+	peerAddr        *peerAddrWatch
+	peerAddrWatched int32
+	jitter          *jitterState
+	backlog         *listenBacklog
+	// handshakeStart is when the listen callback admitted the connection, the
+	// reference of ConnectDuration for accepted sockets
+	handshakeStart time.Time
+	// onIdleTimeout is called with idleTimeoutHandle, see SetIdleTimeoutCallback
+	onIdleTimeout     IdleTimeoutFunc
+	idleTimeoutHandle *SrtSocket
+	kmLogStop         chan struct{}
+	connectDuration   time.Duration
+	// acceptTurn serializes the accepts of a blocking listener, see
+	// AcceptTimeout
+	acceptTurn chan struct{}
+}
+
+func newSocketState() *socketState {
+	return &socketState{acceptTurn: make(chan struct{}, 1)}
+}
+
+// release drops the state of a closed socket and stops its key material logger
+func (st *socketState) release() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.kmLogStop != nil {
+		close(st.kmLogStop)
+		st.kmLogStop = nil
+	}
+	st.userData = nil
+	st.peerAddr = nil
+	atomic.StoreInt32(&st.peerAddrWatched, 0)
+	st.jitter = nil
+	st.backlog = nil
+	st.onIdleTimeout = nil
+	st.idleTimeoutHandle = nil
+}
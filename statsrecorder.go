@@ -0,0 +1,193 @@
+package srtgo
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StatsSample is the statistics of one interval recorded by a StatsRecorder.
+// The local measurements of Stats cover the interval only.
+type StatsSample struct {
+	Time     time.Time     // end of the interval
+	Interval time.Duration // length of the interval
+	Stats    *SrtStats
+}
+
+// StatsWindow summarizes the samples retained by a StatsRecorder
+type StatsWindow struct {
+	Samples        int           // number of intervals in the window
+	Duration       time.Duration // total length of the intervals
+	PktSentPerSec  float64       // data packets sent per second, including retransmissions
+	PktRecvPerSec  float64       // packets received per second
+	LossPercent    float64       // packets lost on the receiving side, relative to received + lost
+	RetransPercent float64       // retransmitted packets relative to sent packets
+	MinRTT         time.Duration // smallest RTT sampled
+	MaxRTT         time.Duration // largest RTT sampled
+	AvgRTT         time.Duration // average of the RTT samples
+}
+
+// StatsRecorder samples the statistics of a socket at a fixed interval and
+// retains the last intervals, from which Window computes rates and trends.
+// It reads the statistics with Stats, which resets the local (per interval)
+// measurements, so other readers of the same socket should use StatsNoClear.
+//
+// The recorder stops by itself once the socket is closed or broken, after
+// recording the final interval, or when Stop is called.
+type StatsRecorder struct {
+	sample func() (*SrtStats, error)
+	closed func() bool
+
+	mu      sync.Mutex
+	samples []StatsSample // ring buffer
+	next    int
+	count   int
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewStatsRecorder starts sampling the statistics of s every interval,
+// retaining the last size samples
+func NewStatsRecorder(s *SrtSocket, interval time.Duration, size int) (*StatsRecorder, error) {
+	// The goroutine works on a copy of the handle, Close may reset s.socket
+	// concurrently; closing is seen through the flag shared by all copies
+	h := *s
+	closed := func() bool {
+		if h.isClosed() {
+			return true
+		}
+		state := C.srt_getsockstate(h.socket)
+		return state == C.SRTS_BROKEN || state == C.SRTS_CLOSED || state == C.SRTS_NONEXIST
+	}
+	return newStatsRecorder(h.Stats, closed, interval, size)
+}
+
+func newStatsRecorder(sample func() (*SrtStats, error), closed func() bool, interval time.Duration, size int) (*StatsRecorder, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid stats interval %s", interval)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid stats window size %d", size)
+	}
+	r := &StatsRecorder{
+		sample:  sample,
+		closed:  closed,
+		samples: make([]StatsSample, size),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	// Start a fresh interval
+	if _, err := sample(); err != nil {
+		return nil, err
+	}
+	go r.run(interval)
+	return r, nil
+}
+
+func (r *StatsRecorder) run(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-ticker.C:
+			stats, err := r.sample()
+			if err != nil {
+				return
+			}
+			r.record(StatsSample{Time: now, Interval: now.Sub(last), Stats: stats})
+			last = now
+			if r.closed() {
+				return
+			}
+		}
+	}
+}
+
+func (r *StatsRecorder) record(sample StatsSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.count < len(r.samples) {
+		r.count++
+	}
+}
+
+// Samples returns the retained samples, oldest first
+func (r *StatsRecorder) Samples() []StatsSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	samples := make([]StatsSample, 0, r.count)
+	start := (r.next - r.count + len(r.samples)) % len(r.samples)
+	for i := 0; i < r.count; i++ {
+		samples = append(samples, r.samples[(start+i)%len(r.samples)])
+	}
+	return samples
+}
+
+// Window returns the rates and RTT range over the retained samples
+func (r *StatsRecorder) Window() StatsWindow {
+	return statsWindow(r.Samples())
+}
+
+// Stop ends the sampling and waits for the sampling goroutine to return.
+// The retained samples remain available.
+func (r *StatsRecorder) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+func statsWindow(samples []StatsSample) StatsWindow {
+	w := StatsWindow{Samples: len(samples)}
+	if len(samples) == 0 {
+		return w
+	}
+	var sent, recv, lost, retrans int64
+	var rttSum float64
+	w.MinRTT = msDuration(samples[0].Stats.MsRTT)
+	for _, sample := range samples {
+		st := sample.Stats
+		w.Duration += sample.Interval
+		sent += st.PktSent
+		recv += st.PktRecv
+		lost += int64(st.PktRcvLoss)
+		retrans += int64(st.PktRetrans)
+
+		rtt := msDuration(st.MsRTT)
+		rttSum += st.MsRTT
+		if rtt < w.MinRTT {
+			w.MinRTT = rtt
+		}
+		if rtt > w.MaxRTT {
+			w.MaxRTT = rtt
+		}
+	}
+	w.AvgRTT = msDuration(rttSum / float64(len(samples)))
+	if secs := w.Duration.Seconds(); secs > 0 {
+		w.PktSentPerSec = float64(sent) / secs
+		w.PktRecvPerSec = float64(recv) / secs
+	}
+	if recv+lost > 0 {
+		w.LossPercent = float64(lost) * 100 / float64(recv+lost)
+	}
+	if sent > 0 {
+		w.RetransPercent = float64(retrans) * 100 / float64(sent)
+	}
+	return w
+}
+
+func msDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,99 @@
+package srtgo
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatsWindow(t *testing.T) {
+	samples := []StatsSample{
+		{Interval: time.Second, Stats: &SrtStats{PktSent: 100, PktRetrans: 5, PktRecv: 90, PktRcvLoss: 10, MsRTT: 20}},
+		{Interval: time.Second, Stats: &SrtStats{PktSent: 300, PktRetrans: 15, PktRecv: 290, PktRcvLoss: 10, MsRTT: 40}},
+	}
+	w := statsWindow(samples)
+	if w.Samples != 2 || w.Duration != 2*time.Second {
+		t.Errorf("unexpected window size %d/%s", w.Samples, w.Duration)
+	}
+	if w.PktSentPerSec != 200 || w.PktRecvPerSec != 190 {
+		t.Errorf("unexpected rates %f/%f", w.PktSentPerSec, w.PktRecvPerSec)
+	}
+	if w.LossPercent != 5 || w.RetransPercent != 5 {
+		t.Errorf("unexpected loss %f%% / retrans %f%%", w.LossPercent, w.RetransPercent)
+	}
+	if w.MinRTT != 20*time.Millisecond || w.MaxRTT != 40*time.Millisecond || w.AvgRTT != 30*time.Millisecond {
+		t.Errorf("unexpected RTT %s/%s/%s", w.MinRTT, w.MaxRTT, w.AvgRTT)
+	}
+
+	if w := statsWindow(nil); w.Samples != 0 || w.PktSentPerSec != 0 {
+		t.Errorf("empty window should be zero, got %+v", w)
+	}
+}
+
+func TestStatsRecorder(t *testing.T) {
+	var calls int64
+	sample := func() (*SrtStats, error) {
+		return &SrtStats{PktSent: atomic.AddInt64(&calls, 1)}, nil
+	}
+	r, err := newStatsRecorder(sample, func() bool { return false }, 5*time.Millisecond, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&calls) < 6 {
+		if time.Now().After(deadline) {
+			t.Fatal("recorder did not sample")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	r.Stop()
+	r.Stop()
+
+	samples := r.Samples()
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 retained samples, got %d", len(samples))
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Stats.PktSent != samples[i-1].Stats.PktSent+1 {
+			t.Errorf("samples out of order: %d after %d", samples[i].Stats.PktSent, samples[i-1].Stats.PktSent)
+		}
+	}
+	stopped := atomic.LoadInt64(&calls)
+	time.Sleep(20 * time.Millisecond)
+	if atomic.LoadInt64(&calls) != stopped {
+		t.Error("recorder kept sampling after Stop")
+	}
+}
+
+func TestStatsRecorderStopsWhenClosed(t *testing.T) {
+	r, err := newStatsRecorder(func() (*SrtStats, error) { return &SrtStats{}, nil }, func() bool { return true }, time.Millisecond, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-r.done:
+	case <-time.After(time.Second):
+		t.Fatal("recorder did not stop with the socket")
+	}
+	if n := len(r.Samples()); n != 1 {
+		t.Errorf("expected the final sample to be recorded, got %d", n)
+	}
+}
+
+func TestStatsRecorderSocketClose(t *testing.T) {
+	caller, remote := connectedPair(t, map[string]string{"transtype": "live"})
+	defer remote.Close()
+
+	r, err := NewStatsRecorder(caller, 5*time.Millisecond, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Close runs concurrently with the sampling goroutine
+	time.Sleep(20 * time.Millisecond)
+	caller.Close()
+	select {
+	case <-r.done:
+	case <-time.After(time.Second):
+		t.Fatal("recorder did not stop after Close")
+	}
+}
@@ -0,0 +1,138 @@
+package srtgo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// streamIDPrefix starts a structured streamid as defined by SRT's access control guidelines
+const streamIDPrefix = "#!::"
+
+// StreamMode is the value of the "m" key of a structured streamid
+type StreamMode string
+
+const (
+	// StreamModeRequest - the caller wants to receive a stream (default)
+	StreamModeRequest StreamMode = "request"
+	// StreamModePublish - the caller wants to send a stream
+	StreamModePublish StreamMode = "publish"
+	// StreamModeBidirectional - the caller wants to exchange data in both directions
+	StreamModeBidirectional StreamMode = "bidirectional"
+)
+
+// StreamID is a parsed SRT access control streamid of the form
+// "#!::u=user,r=resource,h=host,s=session,t=type,m=mode".
+type StreamID struct {
+	User      string            // u: user name
+	Resource  string            // r: resource name, e.g. the stream to publish or play
+	HostName  string            // h: host name, for virtual hosting
+	SessionID string            // s: session id
+	Type      string            // t: type of the stream, e.g. "stream", "file" or "auth"
+	Mode      StreamMode        // m: request, publish or bidirectional
+	Extra     map[string]string // any other keys
+}
+
+// ErrMalformedStreamID is returned by ParseStreamID for streamids that start
+// with "#!" but do not follow the access control syntax
+var ErrMalformedStreamID = errors.New("malformed streamid")
+
+// ParseStreamID parses a streamid. A streamid that does not start with "#!"
+// is not structured and is returned as the Resource. Values may be
+// percent-encoded, which is required for "," "=" and "%" within values.
+// When the mode is not given it defaults to StreamModeRequest.
+func ParseStreamID(s string) (StreamID, error) {
+	var id StreamID
+	if !strings.HasPrefix(s, "#!") {
+		id.Resource = s
+		id.Mode = StreamModeRequest
+		return id, nil
+	}
+	if !strings.HasPrefix(s, streamIDPrefix) {
+		return id, fmt.Errorf("%w: expected %q prefix", ErrMalformedStreamID, streamIDPrefix)
+	}
+
+	seen := make(map[string]bool)
+	body := s[len(streamIDPrefix):]
+	if body != "" {
+		for _, pair := range strings.Split(body, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return StreamID{}, fmt.Errorf("%w: invalid key-value pair %q", ErrMalformedStreamID, pair)
+			}
+			key := kv[0]
+			if seen[key] {
+				return StreamID{}, fmt.Errorf("%w: duplicate key %q", ErrMalformedStreamID, key)
+			}
+			seen[key] = true
+
+			val, err := url.PathUnescape(kv[1])
+			if err != nil {
+				return StreamID{}, fmt.Errorf("%w: invalid escape in %q", ErrMalformedStreamID, pair)
+			}
+
+			switch key {
+			case "u":
+				id.User = val
+			case "r":
+				id.Resource = val
+			case "h":
+				id.HostName = val
+			case "s":
+				id.SessionID = val
+			case "t":
+				id.Type = val
+			case "m":
+				id.Mode = StreamMode(val)
+			default:
+				if id.Extra == nil {
+					id.Extra = make(map[string]string)
+				}
+				id.Extra[key] = val
+			}
+		}
+	}
+
+	switch id.Mode {
+	case "":
+		id.Mode = StreamModeRequest
+	case StreamModeRequest, StreamModePublish, StreamModeBidirectional:
+	default:
+		return StreamID{}, fmt.Errorf("%w: unknown mode %q", ErrMalformedStreamID, id.Mode)
+	}
+	return id, nil
+}
+
+// escapeStreamIDValue percent-encodes the characters that would break the syntax
+func escapeStreamIDValue(v string) string {
+	return strings.NewReplacer("%", "%25", ",", "%2C", "=", "%3D").Replace(v)
+}
+
+// String builds the structured streamid, with standard keys first in a fixed
+// order followed by extra keys sorted by name. Empty fields are omitted.
+func (id StreamID) String() string {
+	var pairs []string
+	add := func(key, val string) {
+		if val != "" {
+			pairs = append(pairs, key+"="+escapeStreamIDValue(val))
+		}
+	}
+	add("u", id.User)
+	add("r", id.Resource)
+	add("h", id.HostName)
+	add("s", id.SessionID)
+	add("t", id.Type)
+	add("m", string(id.Mode))
+
+	keys := make([]string, 0, len(id.Extra))
+	for k := range id.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, id.Extra[k])
+	}
+	return streamIDPrefix + strings.Join(pairs, ",")
+}
@@ -0,0 +1,72 @@
+package srtgo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseStreamID(t *testing.T) {
+	id, err := ParseStreamID("#!::u=admin,r=live/cam1,h=example.com,s=abc123,t=stream,m=publish,x=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := StreamID{
+		User:      "admin",
+		Resource:  "live/cam1",
+		HostName:  "example.com",
+		SessionID: "abc123",
+		Type:      "stream",
+		Mode:      StreamModePublish,
+		Extra:     map[string]string{"x": "1"},
+	}
+	if !reflect.DeepEqual(id, expected) {
+		t.Errorf("expected %+v, got %+v", expected, id)
+	}
+}
+
+func TestParseStreamIDDefaults(t *testing.T) {
+	id, err := ParseStreamID("#!::r=foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Mode != StreamModeRequest {
+		t.Errorf("expected default mode request, got %q", id.Mode)
+	}
+
+	id, err = ParseStreamID("plain-resource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Resource != "plain-resource" {
+		t.Errorf("unstructured streamid should map to the resource, got %+v", id)
+	}
+}
+
+func TestParseStreamIDMalformed(t *testing.T) {
+	for _, s := range []string{"#!:r=foo", "#!::r", "#!::=foo", "#!::r=a,r=b", "#!::m=watch", "#!::r=%zz"} {
+		if _, err := ParseStreamID(s); !errors.Is(err, ErrMalformedStreamID) {
+			t.Errorf("ParseStreamID(%q) expected ErrMalformedStreamID, got %v", s, err)
+		}
+	}
+}
+
+func TestStreamIDRoundTrip(t *testing.T) {
+	id := StreamID{
+		User:     "user",
+		Resource: "a,b=c%d",
+		Mode:     StreamModeBidirectional,
+		Extra:    map[string]string{"z": "2", "y": "1"},
+	}
+	s := id.String()
+	if s != "#!::u=user,r=a%2Cb%3Dc%25d,m=bidirectional,y=1,z=2" {
+		t.Errorf("unexpected streamid %q", s)
+	}
+	parsed, err := ParseStreamID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, id) {
+		t.Errorf("round trip mismatch: expected %+v, got %+v", id, parsed)
+	}
+}
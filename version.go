@@ -0,0 +1,72 @@
+package srtgo
+
+/*
+#cgo LDFLAGS: -lsrt
+#include <srt/srt.h>
+
+// srtgo uses the socket group API and options of SRT 1.5.0, check the headers
+// here to fail with a clear message instead of on the first missing symbol
+#if SRT_VERSION_VALUE < 0x010500
+#error "srtgo requires SRT 1.5.0 or later"
+#endif
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DecodeVersion splits an SRT version encoded as 0xXXYYZZ, as reported by
+// PeerVersion, into its major, minor and patch components.
+func DecodeVersion(v uint32) (major, minor, patch int) {
+	return int(v>>16) & 0xff, int(v>>8) & 0xff, int(v) & 0xff
+}
+
+// EncodeVersion packs a version into the 0xXXYYZZ form used by SRT, e.g.
+// 1.5.0 becomes 0x010500. Every component must be in the range 0-255.
+func EncodeVersion(major, minor, patch int) (uint32, error) {
+	for _, c := range []int{major, minor, patch} {
+		if c < 0 || c > 0xff {
+			return 0, fmt.Errorf("invalid version %d.%d.%d (components must be in range 0-255)", major, minor, patch)
+		}
+	}
+	return uint32(major)<<16 | uint32(minor)<<8 | uint32(patch), nil
+}
+
+// SetMinimumPeerVersion sets the oldest SRT version a peer may run to be
+// allowed to connect (minversion). This is a PRE option, so it must be called
+// before connecting or listening.
+func (s SrtSocket) SetMinimumPeerVersion(major, minor, patch int) error {
+	v, err := EncodeVersion(major, minor, patch)
+	if err != nil {
+		return err
+	}
+	return s.setOption("minversion", strconv.FormatUint(uint64(v), 10))
+}
+
+// MinimumPeerVersion returns the minimum peer version set with
+// SetMinimumPeerVersion or the minversion option
+func (s SrtSocket) MinimumPeerVersion() (major, minor, patch int, err error) {
+	v, err := s.GetSockOptInt(SRTO_MINVERSION)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, minor, patch = DecodeVersion(uint32(v))
+	return major, minor, patch, nil
+}
+
+// Version returns the version of the SRT library linked at runtime, which may
+// differ from the headers srtgo was built against when linking dynamically
+func Version() (major, minor, patch int, raw uint32) {
+	raw = uint32(C.srt_getversion())
+	major, minor, patch = DecodeVersion(raw)
+	return major, minor, patch, raw
+}
+
+// VersionString returns the version of the linked SRT library as
+// "major.minor.patch"
+func VersionString() string {
+	major, minor, patch, _ := Version()
+	return fmt.Sprintf("%d.%d.%d", major, minor, patch)
+}
@@ -0,0 +1,88 @@
+package srtgo
+
+import (
+	"testing"
+)
+
+func TestDecodeVersion(t *testing.T) {
+	tests := []struct {
+		v                   uint32
+		major, minor, patch int
+	}{
+		{0x010301, 1, 3, 1},
+		{0x010401, 1, 4, 1},
+		{0x010502, 1, 5, 2},
+		{0x020000, 2, 0, 0},
+	}
+	for _, tc := range tests {
+		major, minor, patch := DecodeVersion(tc.v)
+		if major != tc.major || minor != tc.minor || patch != tc.patch {
+			t.Errorf("DecodeVersion(%#x) = %d.%d.%d, expected %d.%d.%d", tc.v, major, minor, patch, tc.major, tc.minor, tc.patch)
+		}
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	tests := []struct {
+		major, minor, patch int
+		v                   uint32
+	}{
+		{1, 3, 0, 0x010300},
+		{1, 4, 4, 0x010404},
+		{1, 5, 0, 0x010500},
+		{1, 5, 3, 0x010503},
+		{2, 0, 0, 0x020000},
+	}
+	for _, tc := range tests {
+		v, err := EncodeVersion(tc.major, tc.minor, tc.patch)
+		if err != nil {
+			t.Errorf("EncodeVersion(%d, %d, %d): %v", tc.major, tc.minor, tc.patch, err)
+			continue
+		}
+		if v != tc.v {
+			t.Errorf("EncodeVersion(%d, %d, %d) = %#x, expected %#x", tc.major, tc.minor, tc.patch, v, tc.v)
+		}
+		major, minor, patch := DecodeVersion(v)
+		if major != tc.major || minor != tc.minor || patch != tc.patch {
+			t.Errorf("DecodeVersion(%#x) = %d.%d.%d, expected %d.%d.%d", v, major, minor, patch, tc.major, tc.minor, tc.patch)
+		}
+	}
+	for _, c := range [][3]int{{-1, 0, 0}, {1, 256, 0}, {1, 5, 300}} {
+		if _, err := EncodeVersion(c[0], c[1], c[2]); err == nil {
+			t.Errorf("EncodeVersion(%d, %d, %d) should fail", c[0], c[1], c[2])
+		}
+	}
+}
+
+func TestSetMinimumPeerVersion(t *testing.T) {
+	InitSRT()
+	a := NewSrtSocket("localhost", 8090, map[string]string{})
+	if a == nil {
+		t.Fatal("Could not create a srt socket")
+	}
+	defer a.Close()
+
+	if err := a.SetMinimumPeerVersion(1, 4, 2); err != nil {
+		t.Fatal(err)
+	}
+	major, minor, patch, err := a.MinimumPeerVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if major != 1 || minor != 4 || patch != 2 {
+		t.Errorf("expected 1.4.2, got %d.%d.%d", major, minor, patch)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	major, minor, patch, raw := Version()
+	if raw == 0 {
+		t.Fatal("expected a non-zero library version")
+	}
+	if major == 0 && minor == 0 && patch == 0 {
+		t.Errorf("invalid version decoded from %#x", raw)
+	}
+	if VersionString() == "" {
+		t.Error("expected a version string")
+	}
+}
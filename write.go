@@ -16,7 +16,10 @@ int srt_sendmsg2_wrapped(SRTSOCKET u, const char* buf, int len, SRT_MSGCTRL *mct
 */
 import "C"
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"syscall"
 	"unsafe"
 )
@@ -36,25 +39,160 @@ func srtSendMsg2Impl(u C.SRTSOCKET, buf []byte, msgctrl *C.SRT_MSGCTRL) (n int,
 	return
 }
 
-// Write data to the SRT socket
+// Write data to the SRT socket. In message mode (see MessageAPI) b is sent as
+// one message, which in live mode must fit in a single packet (payloadsize).
+// A message is sent all or nothing: on error n is 0.
+// In stream mode b is appended to the byte stream and may be split or merged
+// with adjacent writes on the receiving side. SRT may accept only part of b
+// when the send buffer is nearly full; Write then keeps sending the rest, and
+// if an error (e.g. the write deadline) ends it early n reports how many bytes
+// were accepted, so the caller can resume from b[n:].
 func (s SrtSocket) Write(b []byte) (n int, err error) {
+	return s.writeContext(context.Background(), b)
+}
+
+// WriteContext writes like Write, but gives up waiting for room in the send
+// buffer when ctx is done and returns ctx.Err(). Nothing has been sent then in
+// message mode; in stream mode n reports the bytes accepted before.
+// The write deadline still applies. Cancellation only interrupts the wait of a
+// non-blocking socket; a blocking socket is bounded by SetSendTimeout instead,
+// so WriteContext rejects it. A cancelled write leaves the socket usable.
+func (s SrtSocket) WriteContext(ctx context.Context, b []byte) (n int, err error) {
+	if s.writeBlocking {
+		return 0, fmt.Errorf("WriteContext requires a non-blocking socket")
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.writeContext(ctx, b)
+}
+
+func (s SrtSocket) writeContext(ctx context.Context, b []byte) (n int, err error) {
+	defer s.guard(ModeWrite)()
+	if err = s.checkWriteClosed(); err != nil {
+		return 0, err
+	}
+
+	// Only stream mode accepts part of b, message mode sends it whole or fails
+	for {
+		var sent int
+		sent, err = s.writeOnce(ctx, b[n:], nil)
+		n += sent
+		if err != nil || sent == 0 || n >= len(b) {
+			return
+		}
+	}
+}
+
+// writeOnce sends b with srt_sendmsg2, waiting for room in the send buffer
+// first if a non-blocking socket has none
+func (s SrtSocket) writeOnce(ctx context.Context, b []byte, msgctrl *C.SRT_MSGCTRL) (n int, err error) {
 	// Fast path: try writing immediately
-	n, err = srtSendMsg2Impl(s.socket, b, nil)
+	n, err = srtSendMsg2Impl(s.socket, b, msgctrl)
 
 	// If successful or blocking mode, return immediately
-	if err == nil || s.blocking || !errors.Is(err, error(EAsyncSND)) {
+	if err == nil || s.writeBlocking || !errors.Is(err, error(EAsyncSND)) {
 		return
 	}
 
-	// Non-blocking mode: wait for socket to be ready for writing
-	if !s.blocking {
+	// Non-blocking mode: wait for socket to be ready for writing. A wakeup
+	// can be spurious, e.g. when a concurrent writer filled the buffer first,
+	// so the wait is repeated until there is room or the deadline or ctx end
+	// it, like a blocking write.
+	for errors.Is(err, error(EAsyncSND)) {
 		s.pd.reset(ModeWrite)
-		if waitErr := s.pd.wait(ModeWrite); waitErr != nil {
+		if waitErr := s.pd.waitContext(ctx, ModeWrite); waitErr != nil {
 			return 0, waitErr
 		}
 		// Try writing again after waiting
-		n, err = srtSendMsg2Impl(s.socket, b, nil)
+		n, err = srtSendMsg2Impl(s.socket, b, msgctrl)
 	}
+	return
+}
 
+// WriteMsg sends b as one message with the control information in ctrl, the
+// counterpart of ReadInto: MsgTTL drops the message if it cannot be sent in
+// time, SrcTime sets its source time and InOrder its delivery order. After the
+// call ctrl holds the message number SRT assigned. A nil ctrl behaves like
+// Write. Only available in message mode, see MessageAPI.
+//
+// InOrder false allows the receiver to deliver the message as soon as it is
+// complete, ahead of earlier messages still missing packets, e.g. for a
+// control message that should not wait behind a retransmission. SRT only
+// honours this without TSBPD, i.e. in file mode with messageapi, see
+// OutOfOrderDelivery. In live mode TSBPD delivers every packet at its source
+// time plus the latency, so a message cannot jump ahead of the media queued
+// before it whatever InOrder says; one that arrives too late is dropped with
+// tlpktdrop, or delayed until the missing packets are recovered (which NAK
+// reports speed up) without it.
+func (s SrtSocket) WriteMsg(b []byte, ctrl *MsgCtrl) (n int, err error) {
+	if ctrl == nil {
+		return s.Write(b)
+	}
+	messageAPI, err := s.MessageAPI()
+	if err != nil {
+		return 0, err
+	}
+	if !messageAPI {
+		return 0, fmt.Errorf("WriteMsg requires message mode")
+	}
+	defer s.guard(ModeWrite)()
+	if err = s.checkWriteClosed(); err != nil {
+		return 0, err
+	}
+	n, err = s.writeOnce(context.Background(), b, ctrl.toC())
+	ctrl.fromC()
 	return
 }
+
+// writevBufPool holds scratch buffers used by WriteV to gather its input and
+// by WriteString to copy its string
+var writevBufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, defaultPacketSize)
+		return &b
+	},
+}
+
+// WriteV sends the concatenation of bufs as a single SRT message, like
+// writev. A single non-empty buffer is sent without copying; otherwise the
+// buffers are gathered into a pooled scratch buffer, so no allocation is made
+// per call once the pool is warm. Returns an ELargeMsg-wrapped error if the
+// total size exceeds what a single Write can send as one message.
+func (s SrtSocket) WriteV(bufs ...[]byte) (int, error) {
+	total := 0
+	nonEmpty := 0
+	var single []byte
+	for _, b := range bufs {
+		if len(b) > 0 {
+			total += len(b)
+			nonEmpty++
+			single = b
+		}
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	l, err := s.messageLimits()
+	if err != nil {
+		return 0, err
+	}
+	if l.max > 0 && total > l.max {
+		return 0, fmt.Errorf("message of %d bytes exceeds maximum of %d: %w", total, l.max, ELargeMsg)
+	}
+
+	if nonEmpty == 1 {
+		return s.Write(single)
+	}
+
+	bp := writevBufPool.Get().(*[]byte)
+	buf := (*bp)[:0]
+	for _, b := range bufs {
+		buf = append(buf, b...)
+	}
+	n, err := s.Write(buf)
+	*bp = buf
+	writevBufPool.Put(bp)
+	return n, err
+}